@@ -3,6 +3,7 @@ package main
 import (
 	"sports_courses/internal/app/ds"
 	"sports_courses/internal/app/dsn"
+	"sports_courses/internal/app/repository"
 
 	"github.com/joho/godotenv"
 	"gorm.io/driver/postgres"
@@ -21,11 +22,170 @@ func main() {
 
 func MigrateSchema(db *gorm.DB) {
 	err := db.AutoMigrate(&ds.User{})
+	err = db.AutoMigrate(&ds.Coach{})
+	err = db.AutoMigrate(&ds.Course{})
+	err = db.AutoMigrate(&ds.Location{})
+	err = db.AutoMigrate(&ds.Term{})
 	err = db.AutoMigrate(&ds.Group{})
+	err = db.AutoMigrate(&ds.GroupSession{})
 	err = db.AutoMigrate(&ds.Enrollment{})
 	err = db.AutoMigrate(&ds.EnrollmentToGroup{})
+	err = db.AutoMigrate(&ds.GroupImage{})
+	err = db.AutoMigrate(&ds.StudentProfile{})
+	err = db.AutoMigrate(&ds.EnrollmentRule{})
+	err = db.AutoMigrate(&ds.MedicalCertificate{})
+	err = db.AutoMigrate(&ds.AllocationRun{})
+	err = db.AutoMigrate(&ds.SessionInstance{})
+	err = db.AutoMigrate(&ds.Attendance{})
+	err = db.AutoMigrate(&ds.CreditRequirement{})
+	err = db.AutoMigrate(&ds.DomainEvent{})
+	err = db.AutoMigrate(&ds.Notification{})
+	err = db.AutoMigrate(&ds.NotificationPreference{})
+	err = db.AutoMigrate(&ds.BulkModerationJob{})
+	err = db.AutoMigrate(&ds.EnrollmentComment{})
+	err = db.AutoMigrate(&ds.RevokedToken{})
 
 	if err != nil {
 		panic(err)
 	}
+
+	// до появления галереи у группы была только одна картинка в groups.image_name
+	err = db.Exec(`INSERT INTO group_images (group_refer, image_name, position, is_cover)
+		SELECT id, image_name, 0, true FROM groups g
+		WHERE image_name <> '' AND NOT EXISTS (SELECT 1 FROM group_images gi WHERE gi.group_refer = g.id)`).Error
+
+	if err != nil {
+		panic(err)
+	}
+
+	MigrateGroupCoaches(db)
+	MigrateGroupCourses(db)
+	MigrateGroupLocations(db)
+	MigrateGroupSearch(db)
+}
+
+// MigrateGroupCoaches переносит тренеров из полей coach_name, coach_phone и
+// coach_email групп в таблицу coaches и удаляет эти поля.
+func MigrateGroupCoaches(db *gorm.DB) {
+	if !db.Migrator().HasColumn(&ds.Group{}, "coach_name") {
+		return
+	}
+
+	err := db.Exec(`INSERT INTO coaches (name, phone, email)
+		SELECT DISTINCT trim(coach_name), coalesce(coach_phone, ''), coalesce(coach_email, '') FROM groups g
+		WHERE trim(coalesce(coach_name, '')) <> '' AND NOT EXISTS (
+			SELECT 1 FROM coaches c WHERE c.name = trim(g.coach_name)
+			AND c.phone = coalesce(g.coach_phone, '') AND c.email = coalesce(g.coach_email, ''))`).Error
+	if err != nil {
+		panic(err)
+	}
+
+	err = db.Exec(`UPDATE groups g SET coach_refer = c.id FROM coaches c
+		WHERE c.name = trim(g.coach_name) AND c.phone = coalesce(g.coach_phone, '') AND c.email = coalesce(g.coach_email, '')`).Error
+	if err != nil {
+		panic(err)
+	}
+
+	err = db.Exec(`ALTER TABLE groups DROP COLUMN coach_name, DROP COLUMN coach_phone, DROP COLUMN coach_email`).Error
+	if err != nil {
+		panic(err)
+	}
+}
+
+// MigrateGroupCourses переносит курсы из текстового поля groups.course в
+// таблицу courses. Названия, отличающиеся только регистром и пробелами,
+// считаются одним курсом, который получает самое частое написание.
+func MigrateGroupCourses(db *gorm.DB) {
+	err := db.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS idx_courses_name_lower ON courses (lower(name))`).Error
+	if err != nil {
+		panic(err)
+	}
+
+	if !db.Migrator().HasColumn(&ds.Group{}, "course") {
+		return
+	}
+
+	err = db.Exec(`INSERT INTO courses (name, default_capacity)
+		SELECT DISTINCT ON (lower(name)) name, 0 FROM (
+			SELECT trim(course) AS name, count(*) AS uses FROM groups
+			WHERE trim(coalesce(course, '')) <> '' GROUP BY trim(course)
+		) spellings
+		WHERE NOT EXISTS (SELECT 1 FROM courses c WHERE lower(c.name) = lower(spellings.name))
+		ORDER BY lower(name), uses DESC, name`).Error
+	if err != nil {
+		panic(err)
+	}
+
+	err = db.Exec(`UPDATE groups g SET course_refer = c.id FROM courses c WHERE lower(c.name) = lower(trim(g.course))`).Error
+	if err != nil {
+		panic(err)
+	}
+
+	err = db.Exec(`ALTER TABLE groups DROP COLUMN course`).Error
+	if err != nil {
+		panic(err)
+	}
+}
+
+// MigrateGroupLocations переносит места из текстового поля groups.location в
+// таблицу locations. Каждое различное написание становится отдельным местом
+// без помещения, уточнить корпус и помещение можно уже через API.
+func MigrateGroupLocations(db *gorm.DB) {
+	if !db.Migrator().HasColumn("groups", "location") {
+		return
+	}
+
+	err := db.Exec(`INSERT INTO locations (building, room, address, accessibility_notes)
+		SELECT DISTINCT trim(location), '', '', '' FROM groups g
+		WHERE trim(coalesce(location, '')) <> '' AND NOT EXISTS (
+			SELECT 1 FROM locations l WHERE l.building = trim(g.location) AND l.room = '')`).Error
+	if err != nil {
+		panic(err)
+	}
+
+	err = db.Exec(`UPDATE groups g SET location_refer = l.id FROM locations l
+		WHERE l.building = trim(g.location) AND l.room = ''`).Error
+	if err != nil {
+		panic(err)
+	}
+
+	err = db.Exec(`ALTER TABLE groups DROP COLUMN location`).Error
+	if err != nil {
+		panic(err)
+	}
+}
+
+// MigrateGroupSearch создаёт индексы для полнотекстового и нечёткого поиска групп.
+func MigrateGroupSearch(db *gorm.DB) {
+	err := db.Exec(`CREATE EXTENSION IF NOT EXISTS pg_trgm`).Error
+	if err != nil {
+		panic(err)
+	}
+
+	err = db.Exec(`CREATE INDEX IF NOT EXISTS idx_groups_search ON groups USING GIN (` + repository.GroupSearchVector + `)`).Error
+	if err != nil {
+		panic(err)
+	}
+
+	err = db.Exec(`CREATE INDEX IF NOT EXISTS idx_groups_trigram ON groups USING GIN (` + repository.GroupTrigramDocument + ` gin_trgm_ops)`).Error
+	if err != nil {
+		panic(err)
+	}
+
+	err = db.Exec(`CREATE INDEX IF NOT EXISTS idx_groups_title_trigram ON groups USING GIN (title gin_trgm_ops)`).Error
+	if err != nil {
+		panic(err)
+	}
+
+	for _, table := range []string{"coaches", "courses"} {
+		err = db.Exec(`CREATE INDEX IF NOT EXISTS idx_` + table + `_search ON ` + table + ` USING GIN (` + repository.NameSearchVector + `)`).Error
+		if err != nil {
+			panic(err)
+		}
+
+		err = db.Exec(`CREATE INDEX IF NOT EXISTS idx_` + table + `_trigram ON ` + table + ` USING GIN (` + repository.NameTrigramDocument + ` gin_trgm_ops)`).Error
+		if err != nil {
+			panic(err)
+		}
+	}
 }
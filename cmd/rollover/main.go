@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"sports_courses/internal/app/config"
+	"sports_courses/internal/app/ds"
+	"sports_courses/internal/app/dsn"
+	"sports_courses/internal/app/redis"
+	"sports_courses/internal/app/repository"
+
+	"github.com/joho/godotenv"
+)
+
+// rollover копирует группы одного семестра в другой, как POST /term/rollover.
+//
+//	go run ./cmd/rollover -from 1 -to 2 -dry-run
+//	go run ./cmd/rollover -from 1 -to 2 -groups 3,5 -overrides overrides.json
+//
+// Файл overrides содержит JSON-массив ds.GroupRolloverOverride. После переноса
+// сбрасывается кэш каталога в redis, как и при переносе через API.
+func main() {
+	from := flag.Uint("from", 0, "id исходного семестра")
+	to := flag.Uint("to", 0, "id семестра, в который переносятся группы")
+	groups := flag.String("groups", "", "id групп через запятую (по умолчанию все действующие)")
+	overridesPath := flag.String("overrides", "", "JSON-файл с заменами полей отдельных групп")
+	dryRun := flag.Bool("dry-run", false, "только показать, что будет создано")
+	flag.Parse()
+
+	if *from == 0 || *to == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	request := ds.RolloverRequestBody{
+		FromTermID: *from,
+		ToTermID:   *to,
+		DryRun:     *dryRun,
+	}
+
+	for _, id := range strings.Split(*groups, ",") {
+		if strings.TrimSpace(id) == "" {
+			continue
+		}
+
+		value, err := strconv.ParseUint(strings.TrimSpace(id), 10, 64)
+		if err != nil {
+			log.Fatalf("некорректный id группы %q", id)
+		}
+		request.GroupIDs = append(request.GroupIDs, uint(value))
+	}
+
+	if *overridesPath != "" {
+		data, err := os.ReadFile(*overridesPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if err := json.Unmarshal(data, &request.Overrides); err != nil {
+			log.Fatal("не получается прочитать замены: ", err)
+		}
+	}
+
+	_ = godotenv.Load()
+	repo, err := repository.New(dsn.FromEnv())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	report, err := repo.RolloverGroups(request)
+	if err != nil && !errors.Is(err, repository.ErrRolloverFailed) {
+		log.Fatal(err)
+	}
+
+	if !request.DryRun && report.Created > 0 {
+		if cacheErr := invalidateGroupsCache(); cacheErr != nil {
+			log.Println("Не получается сбросить кэш каталога, новые группы появятся в нём после истечения TTL:", cacheErr)
+			if err == nil {
+				err = cacheErr
+			}
+		}
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		log.Fatal(err)
+	}
+
+	if err != nil {
+		log.Println(err)
+		os.Exit(1)
+	}
+}
+
+// invalidateGroupsCache увеличивает версию кэша каталога групп в redis.
+func invalidateGroupsCache() error {
+	ctx := context.Background()
+
+	cfg, err := config.NewConfig(ctx)
+	if err != nil {
+		return err
+	}
+
+	client, err := redis.New(ctx, cfg.Redis)
+	if err != nil {
+		return err
+	}
+
+	return client.InvalidateCache(ctx, redis.GroupsCacheNamespace)
+}
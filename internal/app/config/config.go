@@ -16,8 +16,10 @@ type Config struct {
 	ServiceHost string
 	ServicePort int
 
-	JWT   JWTConfig
-	Redis RedisConfig
+	JWT        JWTConfig
+	Redis      RedisConfig
+	CheckIn    CheckInConfig
+	Moderation ModerationConfig
 }
 
 type RedisConfig struct {
@@ -27,16 +29,60 @@ type RedisConfig struct {
 	User        string
 	DialTimeout time.Duration
 	ReadTimeout time.Duration
+
+	GroupsCacheTTL time.Duration
+
+	// ConnectTimeout - сколько при старте ждать ответа redis, прежде чем
+	// запуститься без него
+	ConnectTimeout   time.Duration
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+
+	// BlacklistFailPolicy - что делать с токеном, если блэклист недоступен:
+	// "open" пропускает запрос, "closed" отклоняет его
+	BlacklistFailPolicy string
+	BlacklistCacheTTL   time.Duration
 }
 
 type JWTConfig struct {
 }
 
+type CheckInConfig struct {
+	// Secret подписывает коды отметки на занятии. Без него, в конфиге или в
+	// CHECKIN_SECRET, сервис не запускается: коды должны проверяться всеми
+	// экземплярами и после перезапуска
+	Secret string
+	// CodeTTL - сколько действует один код
+	CodeTTL time.Duration
+	// GeofenceRadius - в скольких метрах от места занятия нужно находиться,
+	// чтобы отметиться; 0 отключает проверку. Если не задан, проверка включена
+	GeofenceRadius float64
+}
+
+type ModerationConfig struct {
+	// ClaimTTL - сколько модератор держит взятую в работу запись, если не
+	// продлевает её
+	ClaimTTL time.Duration
+}
+
+const (
+	BlacklistFailOpen   = "open"
+	BlacklistFailClosed = "closed"
+)
+
 const (
 	envRedisHost = "REDIS_HOST"
 	envRedisPort = "REDIS_PORT"
 	envRedisUser = "REDIS_USER"
 	envRedisPass = "REDIS_PASSWORD"
+
+	envCheckInSecret = "CHECKIN_SECRET"
+)
+
+const (
+	defaultCheckInCodeTTL = 90 * time.Second
+	defaultGeofenceRadius = 150
+	defaultClaimTTL       = 10 * time.Minute
 )
 
 func NewConfig(ctx context.Context) (*Config, error) {
@@ -75,6 +121,34 @@ func NewConfig(ctx context.Context) (*Config, error) {
 	cfg.Redis.Password = ""    // os.Getenv(envRedisPass)
 	cfg.Redis.User = "default" // os.Getenv(envRedisUser)
 
+	switch cfg.Redis.BlacklistFailPolicy {
+	case "":
+		cfg.Redis.BlacklistFailPolicy = BlacklistFailOpen
+	case BlacklistFailOpen, BlacklistFailClosed:
+	default:
+		return nil, fmt.Errorf("unknown redis blacklist fail policy %q", cfg.Redis.BlacklistFailPolicy)
+	}
+
+	if secret := os.Getenv(envCheckInSecret); secret != "" {
+		cfg.CheckIn.Secret = secret
+	}
+
+	if cfg.CheckIn.Secret == "" {
+		return nil, fmt.Errorf("check-in secret is not set, set %s", envCheckInSecret)
+	}
+
+	if cfg.CheckIn.CodeTTL <= 0 {
+		cfg.CheckIn.CodeTTL = defaultCheckInCodeTTL
+	}
+
+	if !viper.IsSet("CheckIn.GeofenceRadius") {
+		cfg.CheckIn.GeofenceRadius = defaultGeofenceRadius
+	}
+
+	if cfg.Moderation.ClaimTTL <= 0 {
+		cfg.Moderation.ClaimTTL = defaultClaimTTL
+	}
+
 	log.Info("config parsed")
 
 	return cfg, nil
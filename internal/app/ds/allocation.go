@@ -0,0 +1,73 @@
+package ds
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Значения EnrollmentToGroup.Availability, которые выставляет распределение.
+const (
+	AvailabilityAssigned  = "Зачислен"
+	AvailabilityNoSeats   = "Нет мест"
+	AvailabilityNotNeeded = "Не требуется"
+)
+
+type SetPreferencesRequestBody struct {
+	EnrollmentID int
+	// GroupIDs - все группы записи в порядке предпочтения, первая - самая желанная
+	GroupIDs []int
+}
+
+type AllocationRequestBody struct {
+	TermID uint
+	// Seed задаёт порядок жеребьёвки. С тем же Seed и теми же записями
+	// распределение повторяется в точности, 0 - выбрать случайно.
+	Seed int64
+	// MaxGroupsPerStudent - сколько групп может получить один студент, по умолчанию 1
+	MaxGroupsPerStudent int
+	DryRun              bool
+}
+
+// GroupAllocation - итог распределения по одной группе.
+type GroupAllocation struct {
+	GroupID     uint
+	Title       string
+	Capacity    int
+	Enrolled    int
+	Applicants  int
+	FirstChoice int
+	Assigned    int
+	Remaining   int
+}
+
+// EnrollmentAllocation - итог распределения по одной записи.
+type EnrollmentAllocation struct {
+	EnrollmentID    uint
+	UserRefer       uuid.UUID
+	LotteryPosition int
+	Assigned        []uint
+	Status          string
+}
+
+type AllocationReport struct {
+	RunID       uint `json:",omitempty"`
+	TermID      uint
+	Seed        int64
+	DryRun      bool
+	Completed   int
+	Rejected    int
+	Groups      []GroupAllocation
+	Enrollments []EnrollmentAllocation
+}
+
+// AllocationRun - сохранённый запуск распределения, чтобы итог можно было
+// посмотреть и повторить позже.
+type AllocationRun struct {
+	ID        uint             `gorm:"primaryKey;AUTO_INCREMENT"`
+	TermRefer uint             `gorm:"not null;index"`
+	Seed      int64            `gorm:"not null"`
+	RunBy     uuid.UUID        `gorm:"type:uuid;not null"`
+	RunAt     time.Time        `gorm:"not null" swaggertype:"primitive,string"`
+	Report    AllocationReport `gorm:"serializer:json"`
+}
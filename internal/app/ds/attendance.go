@@ -0,0 +1,148 @@
+package ds
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Отметки посещаемости.
+const (
+	AttendancePresent = "Присутствовал"
+	AttendanceAbsent  = "Отсутствовал"
+	AttendanceExcused = "Уважительная причина"
+)
+
+var AttendanceStatuses = []string{AttendancePresent, AttendanceAbsent, AttendanceExcused}
+
+// SessionInstance - конкретное занятие группы в определённый день, созданное
+// по еженедельному расписанию группы. По нему отмечается посещаемость.
+type SessionInstance struct {
+	ID            uint      `gorm:"primaryKey;AUTO_INCREMENT"`
+	GroupRefer    int       `gorm:"not null;uniqueIndex:idx_session_instance"`
+	SessionRefer  *uint     `gorm:"index"`
+	Date          time.Time `gorm:"type:date;not null;uniqueIndex:idx_session_instance" swaggertype:"primitive,string"`
+	StartTime     string    `gorm:"type:varchar(5);not null;uniqueIndex:idx_session_instance"`
+	EndTime       string    `gorm:"type:varchar(5);not null"`
+	LocationRefer *uint     `gorm:"index"`
+	Location      *Location `gorm:"foreignKey:LocationRefer" json:",omitempty"`
+	Cancelled     bool      `gorm:"not null;default:false"`
+	// Reason - почему занятие отменили или перенесли
+	Reason string `gorm:"type:text"`
+	// OriginalDate и OriginalStartTime заполняются при переносе занятия, чтобы
+	// повторное создание занятий по расписанию не вернуло его на старое место
+	OriginalDate      *time.Time `gorm:"type:date" swaggertype:"primitive,string" json:",omitempty"`
+	OriginalStartTime string     `gorm:"type:varchar(5)" json:",omitempty"`
+}
+
+// Attendance - отметка студента на занятии.
+type Attendance struct {
+	ID                   uint      `gorm:"primaryKey;AUTO_INCREMENT"`
+	SessionInstanceRefer uint      `gorm:"not null;uniqueIndex:idx_attendance"`
+	UserRefer            uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_attendance;index"`
+	Status               string    `gorm:"type:varchar(50);not null"`
+	MarkedBy             uuid.UUID `gorm:"type:uuid;not null"`
+	MarkedAt             time.Time `gorm:"not null" swaggertype:"primitive,string"`
+	User                 User      `gorm:"foreignKey:UserRefer;references:UUID"`
+}
+
+type GenerateSessionsRequestBody struct {
+	GroupID int
+	// From и To по умолчанию берутся из дат семестра группы
+	From time.Time `swaggertype:"primitive,string"`
+	To   time.Time `swaggertype:"primitive,string"`
+}
+
+type AttendanceMark struct {
+	UserUUID uuid.UUID
+	Status   string
+}
+
+type MarkAttendanceRequestBody struct {
+	SessionID uint
+	Marks     []AttendanceMark
+}
+
+// StudentAttendance - занятие группы студента вместе с его отметкой. Status
+// пустой, если отметки ещё нет.
+type StudentAttendance struct {
+	SessionID  uint
+	GroupID    uint
+	GroupTitle string
+	Date       time.Time `swaggertype:"primitive,string"`
+	StartTime  string
+	EndTime    string
+	Cancelled  bool
+	Status     string
+}
+
+type StudentAttendanceStats struct {
+	UserUUID uuid.UUID
+	Name     string
+	Present  int
+	Absent   int
+	Excused  int
+	Unmarked int
+	// Rate - доля посещённых занятий среди прошедших, без учёта уважительных пропусков
+	Rate float64
+}
+
+type GroupAttendanceStats struct {
+	GroupID  uint
+	Sessions int
+	Held     int
+	Rate     float64
+	Students []StudentAttendanceStats
+}
+
+// CheckInCode - подписанный код самостоятельной отметки на занятии, который
+// тренер показывает студентам в виде QR-кода.
+type CheckInCode struct {
+	SessionID uint
+	Code      string
+	ExpiresAt time.Time `swaggertype:"primitive,string"`
+}
+
+type CheckInRequestBody struct {
+	Code string
+	// Координаты студента, нужны, если включена проверка местоположения
+	Latitude  *float64
+	Longitude *float64
+}
+
+// CancelSessionsRequestBody отменяет одно занятие SessionID или все занятия
+// группы GroupID с From по To. Без GroupID отменяются занятия всех групп за
+// период, например на праздники.
+type CancelSessionsRequestBody struct {
+	SessionID uint
+	GroupID   int
+	From      time.Time `swaggertype:"primitive,string"`
+	To        time.Time `swaggertype:"primitive,string"`
+	Reason    string
+}
+
+type MoveSessionRequestBody struct {
+	SessionID uint
+	Date      time.Time `swaggertype:"primitive,string"`
+	StartTime string
+	EndTime   string
+	// LocationID - новое место, если занятие переносится в другое место
+	LocationID *uint
+	Reason     string
+}
+
+// ScheduleEntry - занятие в расписании студента.
+type ScheduleEntry struct {
+	SessionID         uint
+	GroupID           uint
+	GroupTitle        string
+	Date              time.Time `swaggertype:"primitive,string"`
+	StartTime         string
+	EndTime           string
+	Building          string
+	Room              string
+	Cancelled         bool
+	Reason            string
+	OriginalDate      *time.Time `swaggertype:"primitive,string" json:",omitempty"`
+	OriginalStartTime string     `json:",omitempty"`
+}
@@ -0,0 +1,68 @@
+package ds
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Статусы фоновой массовой модерации.
+const (
+	BulkJobQueued   = "В очереди"
+	BulkJobRunning  = "Выполняется"
+	BulkJobFinished = "Завершена"
+	// BulkJobFailed - задача прервалась вместе с сервисом и не будет продолжена
+	BulkJobFailed = "Прервана"
+)
+
+// BulkModerationFilter выбирает сформированные записи, если ID не перечислены явно.
+type BulkModerationFilter struct {
+	TermID       uint
+	StudyGroup   string
+	Faculty      string
+	Year         int
+	MedicalGroup string
+}
+
+type BulkModerationRequestBody struct {
+	// EnrollmentIDs - записи для обработки. Если пусто, записи выбираются по Filter
+	EnrollmentIDs []uint
+	Filter        *BulkModerationFilter
+	Confirm       bool
+	// ReasonCode и Comment - причина отклонения, см. RejectionRequestBody
+	ReasonCode string
+	Comment    string
+	// Async - обработать в фоне и сразу вернуть задачу
+	Async bool
+}
+
+// BulkModerationResult - итог обработки одной записи. Error пуст, если
+// запись переведена в статус Status.
+type BulkModerationResult struct {
+	EnrollmentID uint
+	Status       string `json:",omitempty"`
+	Error        string `json:",omitempty"`
+}
+
+type BulkModerationReport struct {
+	Total    int
+	Approved int
+	Rejected int
+	Failed   int
+	Results  []BulkModerationResult
+}
+
+// BulkModerationJob - массовая модерация, запущенная в фоне. Report
+// обновляется по ходу обработки.
+type BulkModerationJob struct {
+	ID             uint                 `gorm:"primaryKey;AUTO_INCREMENT"`
+	ModeratorRefer uuid.UUID            `gorm:"type:uuid;not null;index"`
+	Status         string               `gorm:"type:varchar(50);not null"`
+	Confirm        bool                 `gorm:"not null"`
+	ReasonCode     string               `gorm:"type:varchar(50);not null;default:''"`
+	Comment        string               `gorm:"type:text;not null;default:''"`
+	Processed      int                  `gorm:"not null;default:0"`
+	Report         BulkModerationReport `gorm:"serializer:json"`
+	CreatedAt      time.Time            `gorm:"not null" swaggertype:"primitive,string"`
+	FinishedAt     *time.Time           `swaggertype:"primitive,string"`
+}
@@ -0,0 +1,12 @@
+package ds
+
+import "github.com/google/uuid"
+
+type Coach struct {
+	ID        uint       `gorm:"primaryKey;AUTO_INCREMENT"`
+	UserRefer *uuid.UUID `gorm:"type:uuid;unique"`
+	Name      string     `gorm:"type:varchar(200);not null"`
+	Phone     string     `gorm:"type:varchar(35)"`
+	Email     string     `gorm:"type:varchar(100)"`
+	User      User       `gorm:"foreignKey:UserRefer;references:UUID" json:"-"`
+}
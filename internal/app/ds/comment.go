@@ -0,0 +1,41 @@
+package ds
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RejectionReasons - коды причин отклонения записи и их описания для студента.
+var RejectionReasons = map[string]string{
+	"no_seats":  "Нет свободных мест",
+	"medical":   "Не подходит медицинская группа",
+	"schedule":  "Занятия пересекаются с учебным расписанием",
+	"documents": "Не хватает документов",
+	"duplicate": "Повторная запись",
+	"other":     "Другая причина",
+}
+
+// RejectionRequestBody - причина отклонения записи. Обязательна, если запись отклоняется.
+type RejectionRequestBody struct {
+	ReasonCode string
+	Comment    string
+}
+
+// EnrollmentComment - сообщение в обсуждении записи. Внутренние заметки
+// видят только модераторы.
+type EnrollmentComment struct {
+	ID              uint      `gorm:"primaryKey;AUTO_INCREMENT"`
+	EnrollmentRefer uint      `gorm:"not null;index"`
+	AuthorRefer     uuid.UUID `gorm:"type:uuid;not null"`
+	AuthorName      string    `gorm:"not null"`
+	Internal        bool      `gorm:"not null;default:false"`
+	Body            string    `gorm:"type:text;not null"`
+	CreatedAt       time.Time `gorm:"not null" swaggertype:"primitive,string"`
+}
+
+type EnrollmentCommentRequestBody struct {
+	Body string
+	// Internal - заметка только для модераторов, студент её не видит
+	Internal bool
+}
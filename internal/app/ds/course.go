@@ -0,0 +1,18 @@
+package ds
+
+type Course struct {
+	ID              uint   `gorm:"primaryKey;AUTO_INCREMENT"`
+	Name            string `gorm:"type:varchar(255);unique;not null"`
+	SportType       string `gorm:"type:varchar(100)"`
+	Description     string `gorm:"type:text"`
+	Requirements    string `gorm:"type:text"`
+	DefaultCapacity int
+	ImageName       string
+}
+
+// CourseSummary - курс вместе с количеством действующих групп и свободных мест в них.
+type CourseSummary struct {
+	Course
+	ActiveGroups int64
+	FreeSeats    int64
+}
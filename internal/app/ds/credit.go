@@ -0,0 +1,42 @@
+package ds
+
+import "github.com/google/uuid"
+
+// Итог зачёта по физкультуре за семестр.
+const (
+	CreditPassed        = "Зачтено"
+	CreditFailed        = "Не зачтено"
+	CreditInProgress    = "В процессе"
+	CreditNoRequirement = "Требования не заданы"
+)
+
+// CreditRequirement - сколько часов нужно набрать за семестр для зачёта.
+// Требование с Year = 0 действует для всех курсов, для которых нет своего.
+type CreditRequirement struct {
+	ID            uint    `gorm:"primaryKey;AUTO_INCREMENT"`
+	TermRefer     uint    `gorm:"not null;uniqueIndex:idx_credit_requirement"`
+	Year          int     `gorm:"not null;default:0;uniqueIndex:idx_credit_requirement"`
+	RequiredHours float64 `gorm:"not null"`
+	// MinAttendanceRate - минимальная доля посещённых занятий от 0 до 1,
+	// пропуски по уважительной причине не учитываются
+	MinAttendanceRate float64 `gorm:"not null;default:0"`
+}
+
+// StudentCredit - зачтённые часы студента за семестр и итог зачёта.
+type StudentCredit struct {
+	UserUUID          uuid.UUID
+	Name              string
+	FullName          string
+	StudyGroup        string
+	Year              int
+	TermID            uint
+	RequirementID     *uint `json:",omitempty"`
+	RequiredHours     float64
+	MinAttendanceRate float64
+	CreditedHours     float64
+	Held              int
+	Present           int
+	Excused           int
+	AttendanceRate    float64
+	Status            string
+}
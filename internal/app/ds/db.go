@@ -8,31 +8,42 @@ import (
 )
 
 type Group struct {
-	ID          uint   `gorm:"primaryKey;AUTO_INCREMENT"`
-	Title       string `gorm:"type:varchar(255);unique;not null"`
-	Course      string `gorm:"type:text"`
-	Schedule    string `gorm:"type:text"`
-	Location    string `gorm:"type:varchar(255);not null"`
-	Status      string `gorm:"type:varchar(50);not null"`
-	CoachName   string `gorm:"type:varchar(200)"`
-	CoachPhone  string `gorm:"type:varchar(35)"`
-	CoachEmail  string `gorm:"type:varchar(100)"`
-	Capacity    json.Number
-	Enrolled    json.Number
-	Description string `gorm:"type:text"`
-	ImageName   string
+	ID            uint   `gorm:"primaryKey;AUTO_INCREMENT"`
+	Title         string `gorm:"type:varchar(255);unique;not null"`
+	CourseRefer   *uint  `gorm:"index"`
+	TermRefer     *uint  `gorm:"index"`
+	Schedule      string `gorm:"type:text"`
+	LocationRefer *uint  `gorm:"index"`
+	Status        string `gorm:"type:varchar(50);not null"`
+	CoachRefer    *uint  `gorm:"index"`
+	Capacity      json.Number
+	Enrolled      json.Number
+	Description   string `gorm:"type:text"`
+	ImageName     string
+	// RequiresClearance - для записи в группу нужна действующая медицинская справка
+	RequiresClearance bool           `gorm:"not null;default:false"`
+	Coach             *Coach         `gorm:"foreignKey:CoachRefer"`
+	Course            *Course        `gorm:"foreignKey:CourseRefer"`
+	Location          *Location      `gorm:"foreignKey:LocationRefer"`
+	Term              *Term          `gorm:"foreignKey:TermRefer"`
+	Sessions          []GroupSession `gorm:"foreignKey:GroupRefer"`
 }
 
 type Enrollment struct {
 	ID             uint       `gorm:"primaryKey;AUTO_INCREMENT"`
 	ModeratorRefer *uuid.UUID `gorm:"type:uuid"`
 	UserRefer      *uuid.UUID `gorm:"type:uuid;not null"`
+	TermRefer      *uint      `gorm:"index"`
 	Status         string     `gorm:"type:varchar(50);not null"`
 	DateCreated    time.Time  `gorm:"not null" swaggertype:"primitive,string"`
 	DateProcessed  time.Time  `swaggertype:"primitive,string"`
 	DateFinished   time.Time  `swaggertype:"primitive,string"`
-	Moderator      User       `gorm:"foreignKey:ModeratorRefer;references:UUID"`
-	User           User       `gorm:"foreignKey:UserRefer;references:UUID;not null"`
+	// RejectionCode и RejectionComment - почему запись отклонена, ключ из RejectionReasons
+	RejectionCode    string  `gorm:"type:varchar(50)" json:",omitempty"`
+	RejectionComment string  `gorm:"type:text" json:",omitempty"`
+	Moderator        User    `gorm:"foreignKey:ModeratorRefer;references:UUID"`
+	User             User    `gorm:"foreignKey:UserRefer;references:UUID;not null"`
+	Groups           []Group `gorm:"-" json:",omitempty"`
 }
 
 type EnrollmentToGroup struct {
@@ -42,4 +53,6 @@ type EnrollmentToGroup struct {
 	Enrollment      Enrollment `gorm:"foreignKey:EnrollmentRefer"`
 	Group           Group      `gorm:"foreignKey:GroupRefer"`
 	Availability    string     `swaggertype:"primitive,string"`
+	// Rank - место группы в предпочтениях студента, 1 - самая желанная, 0 - без ранга
+	Rank int `gorm:"not null;default:0"`
 }
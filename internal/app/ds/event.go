@@ -0,0 +1,54 @@
+package ds
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Типы событий, о которых нужно сообщить пользователю.
+const (
+	EventSessionCancelled        = "session_cancelled"
+	EventSessionMoved            = "session_moved"
+	EventEnrollmentStatusChanged = "enrollment_status_changed"
+	EventEnrollmentComment       = "enrollment_comment"
+	EventWaitlistPromoted        = "waitlist_promoted"
+)
+
+var EventTypes = []string{EventSessionCancelled, EventSessionMoved, EventEnrollmentStatusChanged, EventEnrollmentComment, EventWaitlistPromoted}
+
+// Типы событий потока, у которых нет своих уведомлений.
+const (
+	EventGroupSeatsChanged = "group_seats_changed"
+	EventNotification      = "notification"
+)
+
+// DomainEvent - событие, адресованное пользователю. События пишутся в той же
+// транзакции, что и изменение, которое их вызвало, поэтому не теряются.
+type DomainEvent struct {
+	ID        uint                   `gorm:"primaryKey;AUTO_INCREMENT"`
+	Type      string                 `gorm:"type:varchar(50);not null;index"`
+	UserRefer uuid.UUID              `gorm:"type:uuid;not null;index"`
+	Payload   map[string]interface{} `gorm:"serializer:json"`
+	CreatedAt time.Time              `gorm:"not null" swaggertype:"primitive,string"`
+	// Published - событие уже отправлено в поток событий
+	Published bool `gorm:"not null;default:false;index" json:"-"`
+}
+
+// StreamEvent - событие потока для подключённых клиентов. Событие без
+// UserRefer получают все клиенты.
+type StreamEvent struct {
+	ID        int64
+	Type      string
+	UserRefer *uuid.UUID `json:",omitempty"`
+	Data      interface{}
+}
+
+// GroupSeats - занятость мест группы в событии group_seats_changed.
+type GroupSeats struct {
+	GroupID  uint
+	Title    string
+	Capacity json.Number
+	Enrolled json.Number
+}
@@ -0,0 +1,11 @@
+package ds
+
+type GroupImage struct {
+	ID         uint   `gorm:"primaryKey;AUTO_INCREMENT"`
+	GroupRefer int    `gorm:"not null;index"`
+	Group      Group  `gorm:"foreignKey:GroupRefer" json:"-"`
+	ImageName  string `gorm:"type:varchar(255);not null"`
+	Caption    string `gorm:"type:text"`
+	Position   int    `gorm:"not null"`
+	IsCover    bool   `gorm:"not null;default:false"`
+}
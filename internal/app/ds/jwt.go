@@ -2,6 +2,7 @@ package ds
 
 import (
 	"sports_courses/internal/app/role"
+	"time"
 
 	"github.com/golang-jwt/jwt"
 	"github.com/google/uuid"
@@ -13,3 +14,13 @@ type JWTClaims struct {
 	Scopes             []string  `json:"scopes" json:"scopes"` // список доступов в нашей системе
 	Role               role.Role
 }
+
+// RevokedToken - отозванный при выходе токен. Таблица - надёжная копия
+// блэклиста redis: отзыв сохраняется, даже если redis недоступен, и
+// переносится в redis, когда тот снова поднимется.
+type RevokedToken struct {
+	Token     string    `gorm:"type:text;primaryKey"`
+	ExpiresAt time.Time `gorm:"not null;index"`
+	// Synced - отзыв уже записан в блэклист redis
+	Synced bool `gorm:"not null;default:false;index"`
+}
@@ -0,0 +1,46 @@
+package ds
+
+type Location struct {
+	ID                 uint   `gorm:"primaryKey;AUTO_INCREMENT"`
+	Building           string `gorm:"type:varchar(255);not null"`
+	Room               string `gorm:"type:varchar(100)"`
+	Address            string `gorm:"type:text"`
+	Capacity           int
+	Latitude           float64
+	Longitude          float64
+	AccessibilityNotes string `gorm:"type:text"`
+}
+
+// GroupSession - еженедельное занятие группы. Если у занятия не указано
+// место, оно проходит там же, где и вся группа.
+type GroupSession struct {
+	ID            uint      `gorm:"primaryKey;AUTO_INCREMENT"`
+	GroupRefer    int       `gorm:"not null;index"`
+	Weekday       int       `gorm:"not null"`                 // 1 - понедельник, 7 - воскресенье
+	StartTime     string    `gorm:"type:varchar(5);not null"` // ЧЧ:ММ
+	EndTime       string    `gorm:"type:varchar(5);not null"` // ЧЧ:ММ
+	LocationRefer *uint     `gorm:"index"`
+	Location      *Location `gorm:"foreignKey:LocationRefer" json:",omitempty"`
+}
+
+// LocationSlot - занятие группы в конкретном месте, используется в отчёте о
+// занятости и в описании конфликтов расписания.
+type LocationSlot struct {
+	SessionID  uint
+	GroupID    uint
+	GroupTitle string
+	LocationID uint
+	Weekday    int
+	StartTime  string
+	EndTime    string
+}
+
+type LocationConflict struct {
+	Session  GroupSession
+	Occupied LocationSlot
+}
+
+type LocationOccupancyDay struct {
+	Weekday int
+	Slots   []LocationSlot
+}
@@ -0,0 +1,37 @@
+package ds
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Статусы медицинской справки.
+const (
+	CertificatePending  = "На проверке"
+	CertificateApproved = "Одобрена"
+	CertificateRejected = "Отклонена"
+)
+
+// MedicalCertificate - медицинская справка студента. Файл хранится в minio,
+// одобренная справка действует до ExpiresAt.
+type MedicalCertificate struct {
+	ID             uint       `gorm:"primaryKey;AUTO_INCREMENT"`
+	UserRefer      uuid.UUID  `gorm:"type:uuid;not null;index"`
+	FileName       string     `gorm:"type:varchar(255);not null"`
+	Status         string     `gorm:"type:varchar(50);not null"`
+	DateUploaded   time.Time  `gorm:"not null" swaggertype:"primitive,string"`
+	ModeratorRefer *uuid.UUID `gorm:"type:uuid"`
+	DateProcessed  *time.Time `swaggertype:"primitive,string"`
+	ExpiresAt      *time.Time `swaggertype:"primitive,string"`
+	Comment        string     `gorm:"type:text"`
+	User           User       `gorm:"foreignKey:UserRefer;references:UUID"`
+}
+
+type ReviewCertificateRequestBody struct {
+	CertificateID int
+	Approve       bool
+	// ExpiresAt обязателен при одобрении справки
+	ExpiresAt time.Time `swaggertype:"primitive,string"`
+	Comment   string
+}
@@ -0,0 +1,62 @@
+package ds
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Типы сообщений очереди модерации.
+const (
+	QueueSnapshot            = "snapshot"
+	QueueEnrollmentAdded     = "enrollment_added"
+	QueueEnrollmentProcessed = "enrollment_processed"
+	QueuePresence            = "presence"
+	QueueEnrollmentClaimed   = "enrollment_claimed"
+	QueueEnrollmentReleased  = "enrollment_released"
+)
+
+// Сообщения модератора в очередь модерации.
+const (
+	QueueMessageView  = "view"
+	QueueMessageLeave = "leave"
+)
+
+// ModerationEvent - сообщение, которое получают все модераторы, открывшие
+// очередь сформированных записей.
+type ModerationEvent struct {
+	Type         string
+	EnrollmentID uint       `json:",omitempty"`
+	Status       string     `json:",omitempty"`
+	Moderator    *uuid.UUID `json:",omitempty"`
+	// ExpiresAt - до какого момента запись взята в работу, только в enrollment_claimed
+	ExpiresAt *time.Time `json:",omitempty" swaggertype:"primitive,string"`
+	// Enrollments - очередь целиком, только в snapshot
+	Enrollments []Enrollment `json:",omitempty"`
+	// Presence - какие модераторы какие записи сейчас смотрят, в snapshot и presence
+	Presence []ModeratorPresence `json:",omitempty"`
+}
+
+// ModeratorPresence - модератор, подключённый к очереди. EnrollmentID = 0,
+// если он не открыл ни одну запись.
+type ModeratorPresence struct {
+	ConnectionID  string
+	ModeratorUUID uuid.UUID
+	Name          string
+	EnrollmentID  uint
+	SeenAt        time.Time `swaggertype:"primitive,string"`
+}
+
+// ModerationMessage - сообщение модератора: view - открыл запись, leave - закрыл её.
+type ModerationMessage struct {
+	Type         string
+	EnrollmentID uint
+}
+
+// EnrollmentClaim - запись, взятая модератором в работу. Пока она не
+// истекла, решение по записи может принять только этот модератор.
+type EnrollmentClaim struct {
+	EnrollmentID uint
+	Moderator    uuid.UUID
+	ExpiresAt    time.Time `swaggertype:"primitive,string"`
+}
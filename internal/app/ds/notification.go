@@ -0,0 +1,33 @@
+package ds
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Notification - уведомление во входящих пользователя, созданное по событию.
+type Notification struct {
+	ID         uint                   `gorm:"primaryKey;AUTO_INCREMENT"`
+	UserRefer  uuid.UUID              `gorm:"type:uuid;not null;index"`
+	EventRefer uint                   `gorm:"not null;index"`
+	Type       string                 `gorm:"type:varchar(50);not null"`
+	Title      string                 `gorm:"type:varchar(255);not null"`
+	Body       string                 `gorm:"type:text"`
+	Payload    map[string]interface{} `gorm:"serializer:json"`
+	CreatedAt  time.Time              `gorm:"not null" swaggertype:"primitive,string"`
+	ReadAt     *time.Time             `swaggertype:"primitive,string"`
+}
+
+// NotificationPreference выключает уведомления одного типа для пользователя.
+// Если настройки нет, уведомления этого типа приходят.
+type NotificationPreference struct {
+	UserRefer uuid.UUID `gorm:"type:uuid;primaryKey"`
+	Type      string    `gorm:"type:varchar(50);primaryKey"`
+	Enabled   bool      `gorm:"not null"`
+}
+
+type NotificationPreferenceRequestBody struct {
+	Type    string
+	Enabled bool
+}
@@ -0,0 +1,21 @@
+package ds
+
+type SortField struct {
+	Column string
+	Desc   bool
+}
+
+// PageRequest описывает запрашиваемую страницу списка. Если передан Cursor,
+// Offset игнорируется и страница начинается сразу после строки из курсора.
+type PageRequest struct {
+	Limit  int
+	Offset int
+	Cursor string
+	Sort   []SortField
+}
+
+type PageInfo struct {
+	Total      int64
+	HasMore    bool
+	NextCursor string
+}
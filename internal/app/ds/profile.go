@@ -0,0 +1,56 @@
+package ds
+
+import (
+	"sports_courses/internal/app/role"
+
+	"github.com/google/uuid"
+)
+
+// Медицинские группы здоровья студентов.
+const (
+	MedicalGroupMain        = "основная"
+	MedicalGroupPreparatory = "подготовительная"
+	MedicalGroupSpecialA    = "специальная А"
+	MedicalGroupSpecialB    = "специальная Б"
+)
+
+const (
+	GenderMale   = "мужской"
+	GenderFemale = "женский"
+)
+
+var MedicalGroups = []string{MedicalGroupMain, MedicalGroupPreparatory, MedicalGroupSpecialA, MedicalGroupSpecialB}
+
+// StudentProfile - сведения о студенте. Заполняются самим студентом, видны
+// модераторам в записях и используются при проверке правил записи.
+type StudentProfile struct {
+	UserRefer    uuid.UUID `gorm:"type:uuid;primaryKey"`
+	FullName     string    `gorm:"type:varchar(255)"`
+	StudyGroup   string    `gorm:"type:varchar(50);index"` // например ИУ5-31Б
+	Faculty      string    `gorm:"type:varchar(255)"`
+	Year         int
+	Gender       string `gorm:"type:varchar(20)"`
+	Email        string `gorm:"type:varchar(255)"`
+	Phone        string `gorm:"type:varchar(50)"`
+	MedicalGroup string `gorm:"type:varchar(50)"`
+}
+
+// EditProfileRequestBody - поля профиля, которые студент меняет сам.
+type EditProfileRequestBody struct {
+	FullName     string
+	StudyGroup   string
+	Faculty      string
+	Year         int
+	Gender       string
+	Email        string
+	Phone        string
+	MedicalGroup string
+}
+
+// Me - текущий пользователь вместе с профилем студента.
+type Me struct {
+	UUID    uuid.UUID
+	Name    string
+	Role    role.Role
+	Profile *StudentProfile
+}
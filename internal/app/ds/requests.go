@@ -1,8 +1,9 @@
 package ds
 
+// EnrollRequestBody - группы новой записи. Запись всегда создаётся черновиком
+// и формируется через PUT /enrollment/user_confirm.
 type EnrollRequestBody struct {
 	Groups []string
-	Status string
 }
 
 type EditEnrollmentRequestBody struct {
@@ -17,6 +18,8 @@ type SetEnrollmentGroupsRequestBody struct {
 type ChangeEnrollmentStatusRequestBody struct {
 	EnrollmentID int
 	Status       string
+	// причина нужна, когда модератор отклоняет запись
+	RejectionRequestBody
 }
 
 type ChangeEnrollmentToGroupAvailabilityRequestBody struct {
@@ -28,3 +31,41 @@ type DeleteEnrollmentToGroupRequestBody struct {
 	EnrollmentID int
 	GroupID      int
 }
+
+type EditGroupImageRequestBody struct {
+	ImageID int
+	Caption string
+}
+
+type ReorderGroupImagesRequestBody struct {
+	GroupID  int
+	ImageIDs []int
+}
+
+// EnrollmentExpand перечисляет связанные сущности, которые нужно загрузить
+// вместе с записями (параметр expand=groups,user,moderator).
+type EnrollmentExpand struct {
+	Groups    bool
+	User      bool
+	Moderator bool
+}
+
+type CoachRequestBody struct {
+	CoachID int
+	Name    string
+	Phone   string
+	Email   string
+	// Login - логин пользователя, который станет аккаунтом тренера
+	Login string
+}
+
+type EditCoachContactsRequestBody struct {
+	Name  string
+	Phone string
+	Email string
+}
+
+type EditGroupDescriptionRequestBody struct {
+	GroupID     int
+	Description string
+}
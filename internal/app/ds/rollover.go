@@ -0,0 +1,41 @@
+package ds
+
+import "encoding/json"
+
+// GroupRolloverOverride - поля, которые заменяются у копии группы GroupID при
+// переносе в новый семестр. Пустые поля берутся из исходной группы.
+type GroupRolloverOverride struct {
+	GroupID       uint
+	Title         string
+	Schedule      string
+	Capacity      json.Number
+	CoachRefer    *uint
+	LocationRefer *uint
+	Sessions      []GroupSession
+}
+
+// RolloverRequestBody - параметры переноса групп из семестра FromTermID в
+// семестр ToTermID. Если GroupIDs пуст, переносятся все действующие группы.
+type RolloverRequestBody struct {
+	FromTermID uint
+	ToTermID   uint
+	GroupIDs   []uint
+	DryRun     bool
+	Overrides  []GroupRolloverOverride
+}
+
+// RolloverResult - что произошло (или произойдёт при DryRun) с одной группой.
+type RolloverResult struct {
+	SourceID    uint
+	SourceTitle string
+	Group       Group
+	Images      int
+	Conflicts   []LocationConflict `json:",omitempty"`
+	Error       string             `json:",omitempty"`
+}
+
+type RolloverReport struct {
+	DryRun  bool
+	Created int
+	Groups  []RolloverResult
+}
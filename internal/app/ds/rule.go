@@ -0,0 +1,41 @@
+package ds
+
+// Виды правил записи.
+const (
+	// RuleMaxGroupsPerTerm - не больше MaxGroups групп в одном семестре
+	RuleMaxGroupsPerTerm = "max_groups_per_term"
+	// RuleMaxGroupsPerCourse - не больше MaxGroups групп одного курса
+	RuleMaxGroupsPerCourse = "max_groups_per_course"
+	// RuleMedicalGroup - медицинская группа студента должна быть из Values
+	RuleMedicalGroup = "medical_group"
+	// RuleYearOfStudy - курс обучения студента от MinYear до MaxYear
+	RuleYearOfStudy = "year_of_study"
+	// RuleGender - пол студента должен быть из Values
+	RuleGender = "gender"
+)
+
+// EnrollmentRule - правило, которое проверяется при добавлении групп в запись
+// и при её формировании. TermRefer, CourseRefer и GroupRefer сужают правило до
+// групп семестра, курса или одной группы, пустые - правило действует на все.
+type EnrollmentRule struct {
+	ID          uint   `gorm:"primaryKey;AUTO_INCREMENT"`
+	Kind        string `gorm:"type:varchar(50);not null"`
+	Description string `gorm:"type:text"`
+	Enabled     bool   `gorm:"not null"`
+	TermRefer   *uint  `gorm:"index"`
+	CourseRefer *uint  `gorm:"index"`
+	GroupRefer  *uint  `gorm:"index"`
+	MaxGroups   int
+	MinYear     int
+	MaxYear     int
+	Values      []string `gorm:"serializer:json"`
+}
+
+// RuleViolation - одно нарушенное правило. GroupID указывает группу, из-за
+// которой правило нарушено, если такая есть.
+type RuleViolation struct {
+	RuleID  uint
+	Kind    string
+	GroupID uint `json:",omitempty"`
+	Message string
+}
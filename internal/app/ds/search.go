@@ -0,0 +1,39 @@
+package ds
+
+// GroupFilter описывает параметры поиска групп в каталоге.
+type GroupFilter struct {
+	TitlePattern string
+	Query        string
+	Course       string
+	CourseID     uint
+	Location     string
+	LocationID   uint
+	TermID       uint
+	Weekday      string
+	Status       string
+	HasFreeSeats *bool
+	CoachID      uint
+}
+
+// EnrollmentFilter описывает параметры отбора записей. Поля StudyGroup,
+// Faculty, Year и MedicalGroup фильтруют по профилю студента.
+type EnrollmentFilter struct {
+	Status       string
+	StartDate    string
+	EndDate      string
+	TermID       uint
+	StudyGroup   string
+	Faculty      string
+	Year         int
+	MedicalGroup string
+}
+
+// GroupFacets содержит количество групп для каждого значения фильтра. Счётчики
+// измерения считаются с учётом всех остальных фильтров, кроме его собственного.
+type GroupFacets struct {
+	Courses      map[string]int64 `json:"courses"`
+	Locations    map[string]int64 `json:"locations"`
+	Weekdays     map[string]int64 `json:"weekdays"`
+	Statuses     map[string]int64 `json:"statuses"`
+	HasFreeSeats map[string]int64 `json:"has_free_seats"`
+}
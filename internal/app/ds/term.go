@@ -0,0 +1,19 @@
+package ds
+
+import "time"
+
+// Term - учебный семестр. Записываться в группы семестра можно только с
+// EnrollmentOpensAt до EnrollmentClosesAt.
+type Term struct {
+	ID                 uint      `gorm:"primaryKey;AUTO_INCREMENT"`
+	Name               string    `gorm:"type:varchar(255);unique;not null"`
+	StartDate          time.Time `gorm:"not null" swaggertype:"primitive,string"`
+	EndDate            time.Time `gorm:"not null" swaggertype:"primitive,string"`
+	EnrollmentOpensAt  time.Time `gorm:"not null" swaggertype:"primitive,string"`
+	EnrollmentClosesAt time.Time `gorm:"not null" swaggertype:"primitive,string"`
+}
+
+// EnrollmentOpen сообщает, идёт ли запись в группы семестра в момент now.
+func (t Term) EnrollmentOpen(now time.Time) bool {
+	return !now.Before(t.EnrollmentOpensAt) && now.Before(t.EnrollmentClosesAt)
+}
@@ -10,5 +10,8 @@ type User struct {
 	UUID uuid.UUID `gorm:"type:uuid;unique"`
 	Name string    `json:"name"`
 	Role role.Role `sql:"type:string;"`
-	Pass string    `json:"pass"`
+	// Pass - хэш пароля, в ответы API не попадает
+	Pass string `json:"-"`
+	// Profile загружается отдельно и только вместе с записями
+	Profile *StudentProfile `gorm:"-" json:",omitempty"`
 }
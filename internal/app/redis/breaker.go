@@ -0,0 +1,143 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+var ErrCircuitOpen = errors.New("redis временно недоступен")
+
+const (
+	defaultBreakerThreshold = 5
+	defaultBreakerCooldown  = 10 * time.Second
+)
+
+// breaker - предохранитель между сервисом и redis. После threshold ошибок
+// подряд он размыкается, и команды сразу получают ErrCircuitOpen, не дожидаясь
+// таймаутов. Через cooldown пропускается одна пробная команда: при успехе
+// предохранитель замыкается, при ошибке снова размыкается.
+type breaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu       sync.Mutex
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+func newBreaker(threshold int, cooldown time.Duration) *breaker {
+	if threshold <= 0 {
+		threshold = defaultBreakerThreshold
+	}
+
+	if cooldown <= 0 {
+		cooldown = defaultBreakerCooldown
+	}
+
+	return &breaker{threshold: threshold, cooldown: cooldown}
+}
+
+func (b *breaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failures < b.threshold {
+		return true
+	}
+
+	if b.probing || time.Since(b.openedAt) < b.cooldown {
+		return false
+	}
+
+	b.probing = true
+	return true
+}
+
+func (b *breaker) record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// отменённый клиентом запрос ничего не говорит о состоянии redis
+	if errors.Is(err, context.Canceled) {
+		b.probing = false
+		return
+	}
+
+	// redis.Nil - обычный промах, а не отказ redis
+	if err == nil || errors.Is(err, redis.Nil) {
+		if b.failures >= b.threshold {
+			log.Println("redis снова доступен")
+		}
+		b.failures = 0
+		b.probing = false
+		return
+	}
+
+	b.failures++
+	b.probing = false
+	if b.failures >= b.threshold {
+		if b.failures == b.threshold {
+			log.Println("redis недоступен, запросы к нему временно отключены:", err)
+		}
+		b.openedAt = time.Now()
+	}
+}
+
+func (b *breaker) closed() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.failures < b.threshold
+}
+
+// breakerHook подключает предохранитель ко всем командам клиента go-redis.
+type breakerHook struct {
+	breaker *breaker
+}
+
+func (h breakerHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	if !h.breaker.allow() {
+		return ctx, ErrCircuitOpen
+	}
+
+	return ctx, nil
+}
+
+func (h breakerHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	if !errors.Is(cmd.Err(), ErrCircuitOpen) {
+		h.breaker.record(cmd.Err())
+	}
+
+	return nil
+}
+
+func (h breakerHook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	if !h.breaker.allow() {
+		return ctx, ErrCircuitOpen
+	}
+
+	return ctx, nil
+}
+
+func (h breakerHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	for _, cmd := range cmds {
+		if errors.Is(cmd.Err(), ErrCircuitOpen) {
+			return nil
+		}
+
+		if err := cmd.Err(); err != nil && !errors.Is(err, redis.Nil) {
+			h.breaker.record(err)
+			return nil
+		}
+	}
+
+	h.breaker.record(nil)
+
+	return nil
+}
@@ -0,0 +1,166 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// GroupsCacheNamespace - пространство имён кэша каталога групп.
+const GroupsCacheNamespace = "groups"
+
+const (
+	cachePrefix   = "cache."
+	lockSuffix    = ".lock"
+	cacheLockTTL  = 5 * time.Second
+	cachePollStep = 50 * time.Millisecond
+)
+
+func getCacheKey(key string) string {
+	return servicePrefix + cachePrefix + key
+}
+
+// CacheVersion возвращает текущую версию пространства имён namespace. Версия
+// входит в ключи кэша, поэтому её увеличение разом делает все старые ключи
+// недостижимыми, а сами они доживают до истечения TTL.
+func (c *Client) CacheVersion(ctx context.Context, namespace string) (int64, error) {
+	if err := c.flushInvalidations(ctx); err != nil {
+		return 0, err
+	}
+
+	version, err := c.client.Get(ctx, getCacheKey(namespace+".version")).Int64()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+
+	return version, err
+}
+
+// InvalidateCache увеличивает версию namespace. Если redis недоступен,
+// сброс откладывается до следующего обращения к кэшу, чтобы после
+// восстановления redis не отдавать данные, изменённые во время сбоя.
+func (c *Client) InvalidateCache(ctx context.Context, namespace string) error {
+	err := c.client.Incr(ctx, getCacheKey(namespace+".version")).Err()
+	if err != nil {
+		c.mu.Lock()
+		c.pendingInvalidations[namespace] = true
+		c.mu.Unlock()
+	}
+
+	return err
+}
+
+func (c *Client) flushInvalidations(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for namespace := range c.pendingInvalidations {
+		if err := c.client.Incr(ctx, getCacheKey(namespace+".version")).Err(); err != nil {
+			return err
+		}
+		delete(c.pendingInvalidations, namespace)
+	}
+
+	return nil
+}
+
+// VersionedKey собирает ключ кэша с учётом текущей версии namespace.
+func (c *Client) VersionedKey(ctx context.Context, namespace string, key string) (string, error) {
+	version, err := c.CacheVersion(ctx, namespace)
+	if err != nil {
+		return "", err
+	}
+
+	return namespace + ".v" + strconv.FormatInt(version, 10) + "." + key, nil
+}
+
+// Fetch читает значение из кэша в dest, а при промахе вызывает load и
+// сохраняет результат на ttl. От одновременных промахов по одному ключу
+// защищают singleflight внутри процесса и блокировка в redis между
+// экземплярами сервиса: загружает значение только тот, кто взял блокировку,
+// остальные ждут, пока оно появится в кэше.
+func (c *Client) Fetch(ctx context.Context, key string, ttl time.Duration, dest interface{}, load func() (interface{}, error)) error {
+	cacheKey := getCacheKey(key)
+
+	data, err := c.client.Get(ctx, cacheKey).Bytes()
+	if err == nil {
+		return json.Unmarshal(data, dest)
+	}
+
+	if !errors.Is(err, redis.Nil) {
+		log.Println("Не получается прочитать кэш:", err)
+		return loadInto(dest, load)
+	}
+
+	result, err, _ := c.flight.Do(cacheKey, func() (interface{}, error) {
+		return c.fill(ctx, cacheKey, ttl, load)
+	})
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(result.([]byte), dest)
+}
+
+func (c *Client) fill(ctx context.Context, cacheKey string, ttl time.Duration, load func() (interface{}, error)) ([]byte, error) {
+	locked, err := c.client.SetNX(ctx, cacheKey+lockSuffix, true, cacheLockTTL).Result()
+	if err != nil {
+		log.Println("Не получается взять блокировку кэша:", err)
+		return loadBytes(load)
+	}
+
+	if !locked {
+		deadline := time.Now().Add(cacheLockTTL)
+		for time.Now().Before(deadline) {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(cachePollStep):
+			}
+
+			data, err := c.client.Get(ctx, cacheKey).Bytes()
+			if err == nil {
+				return data, nil
+			}
+		}
+
+		// тот, кто держал блокировку, не успел заполнить кэш - не ждём дальше
+		return loadBytes(load)
+	}
+
+	defer c.client.Del(ctx, cacheKey+lockSuffix)
+
+	data, err := loadBytes(load)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.client.Set(ctx, cacheKey, data, ttl).Err(); err != nil {
+		log.Println("Не получается записать кэш:", err)
+	}
+
+	return data, nil
+}
+
+func loadBytes(load func() (interface{}, error)) ([]byte, error) {
+	value, err := load()
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(value)
+}
+
+func loadInto(dest interface{}, load func() (interface{}, error)) error {
+	data, err := loadBytes(load)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, dest)
+}
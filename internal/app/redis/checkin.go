@@ -0,0 +1,21 @@
+package redis
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const checkInPrefix = "checkin."
+
+func getCheckInKey(code string, userUUID uuid.UUID) string {
+	return servicePrefix + checkInPrefix + code + "." + userUUID.String()
+}
+
+// ClaimCheckIn отмечает, что пользователь уже воспользовался кодом. Возвращает
+// false, если код этим пользователем уже был использован. Ключ живёт столько
+// же, сколько сам код, после этого код отклоняется по сроку действия.
+func (c *Client) ClaimCheckIn(ctx context.Context, code string, userUUID uuid.UUID, ttl time.Duration) (bool, error) {
+	return c.client.SetNX(ctx, getCheckInKey(code, userUUID), true, ttl).Result()
+}
@@ -0,0 +1,94 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+)
+
+const claimPrefix = "claim."
+
+func getClaimKey(enrollment_id uint) string {
+	return servicePrefix + claimPrefix + strconv.FormatUint(uint64(enrollment_id), 10)
+}
+
+// releaseClaimScript удаляет аренду, только если её держит переданный модератор.
+var releaseClaimScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// ClaimEnrollment берёт запись в работу на ttl. Если запись уже держит этот
+// же модератор, аренда продлевается. Если force, аренда другого модератора
+// перебивается. Возвращает того, кто держит запись после вызова, и сколько
+// ещё продлится аренда.
+func (c *Client) ClaimEnrollment(ctx context.Context, enrollment_id uint, moderator uuid.UUID, ttl time.Duration, force bool) (uuid.UUID, time.Duration, error) {
+	key := getClaimKey(enrollment_id)
+
+	if force {
+		return moderator, ttl, c.client.Set(ctx, key, moderator.String(), ttl).Err()
+	}
+
+	ok, err := c.client.SetNX(ctx, key, moderator.String(), ttl).Result()
+	if err != nil {
+		return uuid.Nil, 0, err
+	}
+
+	if ok {
+		return moderator, ttl, nil
+	}
+
+	holder, left, err := c.EnrollmentClaim(ctx, enrollment_id)
+	if err != nil {
+		return uuid.Nil, 0, err
+	}
+
+	if holder == moderator {
+		return moderator, ttl, c.client.PExpire(ctx, key, ttl).Err()
+	}
+
+	return holder, left, nil
+}
+
+// EnrollmentClaim возвращает модератора, который держит запись, и сколько
+// ещё продлится аренда. uuid.Nil - запись никто не держит.
+func (c *Client) EnrollmentClaim(ctx context.Context, enrollment_id uint) (uuid.UUID, time.Duration, error) {
+	key := getClaimKey(enrollment_id)
+
+	value, err := c.client.Get(ctx, key).Result()
+	if errors.Is(err, redis.Nil) {
+		return uuid.Nil, 0, nil
+	}
+
+	if err != nil {
+		return uuid.Nil, 0, err
+	}
+
+	holder, err := uuid.Parse(value)
+	if err != nil {
+		return uuid.Nil, 0, err
+	}
+
+	left, err := c.client.PTTL(ctx, key).Result()
+	if err != nil {
+		return uuid.Nil, 0, err
+	}
+
+	return holder, left, nil
+}
+
+// ReleaseEnrollmentClaim снимает аренду модератора с записи. Если force,
+// аренда снимается, кто бы её ни держал.
+func (c *Client) ReleaseEnrollmentClaim(ctx context.Context, enrollment_id uint, moderator uuid.UUID, force bool) error {
+	if force {
+		return c.client.Del(ctx, getClaimKey(enrollment_id)).Err()
+	}
+
+	return releaseClaimScript.Run(ctx, c.client, []string{getClaimKey(enrollment_id)}, moderator.String()).Err()
+}
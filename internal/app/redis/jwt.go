@@ -2,19 +2,106 @@ package redis
 
 import (
 	"context"
+	"errors"
+	"sync"
 	"time"
+
+	"github.com/go-redis/redis/v8"
 )
 
 const jwtPrefix = "jwt."
 
+const defaultBlacklistCacheTTL = 10 * time.Second
+
 func getJWTKey(token string) string {
 	return servicePrefix + jwtPrefix + token
 }
 
+// WriteJWTToBlackList запоминает токен в локальном блэклисте ещё до записи в
+// redis, чтобы выход из системы действовал на этом экземпляре сервиса, даже
+// если redis сейчас недоступен.
 func (c *Client) WriteJWTToBlackList(ctx context.Context, jwtStr string, jwtTTL time.Duration) error {
+	c.blacklist.set(jwtStr, true, jwtTTL)
+
 	return c.client.Set(ctx, getJWTKey(jwtStr), true, jwtTTL).Err()
 }
 
-func (c *Client) CheckJWTInBlackList(ctx context.Context, jwtStr string) error {
-	return c.client.Get(ctx, getJWTKey(jwtStr)).Err()
+// IsJWTBlacklisted проверяет, отозван ли токен. Ответы redis кэшируются в
+// памяти на cfg.BlacklistCacheTTL. Ошибка возвращается только когда ответа
+// нет ни в кэше, ни в redis - что делать в этом случае, решает вызывающий код.
+func (c *Client) IsJWTBlacklisted(ctx context.Context, jwtStr string) (bool, error) {
+	if blacklisted, ok := c.blacklist.get(jwtStr); ok {
+		return blacklisted, nil
+	}
+
+	err := c.client.Get(ctx, getJWTKey(jwtStr)).Err()
+	if errors.Is(err, redis.Nil) {
+		c.blacklist.set(jwtStr, false, c.blacklist.ttl)
+		return false, nil
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	c.blacklist.set(jwtStr, true, c.blacklist.ttl)
+
+	return true, nil
+}
+
+type blacklistEntry struct {
+	blacklisted bool
+	expiresAt   time.Time
+}
+
+// localBlacklist - кэш ответов блэклиста внутри процесса.
+type localBlacklist struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	entries map[string]blacklistEntry
+	sweptAt time.Time
+}
+
+func newLocalBlacklist(ttl time.Duration) *localBlacklist {
+	if ttl <= 0 {
+		ttl = defaultBlacklistCacheTTL
+	}
+
+	return &localBlacklist{ttl: ttl, entries: map[string]blacklistEntry{}}
+}
+
+func (b *localBlacklist) get(token string) (bool, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	entry, ok := b.entries[token]
+	if !ok || time.Now().After(entry.expiresAt) {
+		return false, false
+	}
+
+	return entry.blacklisted, true
+}
+
+func (b *localBlacklist) set(token string, blacklisted bool, ttl time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+
+	// отрицательный ответ не должен перетирать известный отзыв токена
+	if entry, ok := b.entries[token]; ok && entry.blacklisted && !blacklisted && now.Before(entry.expiresAt) {
+		return
+	}
+
+	b.entries[token] = blacklistEntry{blacklisted: blacklisted, expiresAt: now.Add(ttl)}
+
+	if now.Sub(b.sweptAt) > b.ttl {
+		for key, entry := range b.entries {
+			if now.After(entry.expiresAt) {
+				delete(b.entries, key)
+			}
+		}
+		b.sweptAt = now
+	}
 }
@@ -0,0 +1,81 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"sports_courses/internal/app/ds"
+)
+
+const (
+	moderationChannel  = "moderation"
+	moderationPresence = "moderation.presence"
+)
+
+// PublishModerationEvent рассылает событие очереди модерации всем экземплярам сервиса.
+func (c *Client) PublishModerationEvent(ctx context.Context, event ds.ModerationEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	return c.client.Publish(ctx, servicePrefix+moderationChannel, data).Err()
+}
+
+// SubscribeModerationEvents передаёт в handle события очереди модерации, пока не отменён ctx.
+func (c *Client) SubscribeModerationEvents(ctx context.Context, handle func(ds.ModerationEvent)) error {
+	return c.subscribe(ctx, servicePrefix+moderationChannel, func(payload string) {
+		event := ds.ModerationEvent{}
+		if err := json.Unmarshal([]byte(payload), &event); err != nil {
+			log.Println("Не получается разобрать событие очереди из redis:", err)
+			return
+		}
+
+		handle(event)
+	})
+}
+
+// SetModeratorPresence запоминает, что модератор подключён к очереди и какую
+// запись он смотрит.
+func (c *Client) SetModeratorPresence(ctx context.Context, presence ds.ModeratorPresence) error {
+	data, err := json.Marshal(presence)
+	if err != nil {
+		return err
+	}
+
+	return c.client.HSet(ctx, servicePrefix+moderationPresence, presence.ConnectionID, data).Err()
+}
+
+func (c *Client) RemoveModeratorPresence(ctx context.Context, connectionID string) error {
+	return c.client.HDel(ctx, servicePrefix+moderationPresence, connectionID).Err()
+}
+
+// GetModeratorPresence возвращает подключённых к очереди модераторов.
+// Подключения, которые не обновлялись дольше ttl (например, экземпляр сервиса
+// упал, не успев их убрать), удаляются.
+func (c *Client) GetModeratorPresence(ctx context.Context, ttl time.Duration) ([]ds.ModeratorPresence, error) {
+	values, err := c.client.HGetAll(ctx, servicePrefix+moderationPresence).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	result := []ds.ModeratorPresence{}
+	stale := []string{}
+	for connectionID, value := range values {
+		presence := ds.ModeratorPresence{}
+		if err := json.Unmarshal([]byte(value), &presence); err != nil || time.Since(presence.SeenAt) > ttl {
+			stale = append(stale, connectionID)
+			continue
+		}
+
+		result = append(result, presence)
+	}
+
+	if len(stale) > 0 {
+		c.client.HDel(ctx, servicePrefix+moderationPresence, stale...)
+	}
+
+	return result, nil
+}
@@ -2,20 +2,40 @@ package redis
 
 import (
 	"context"
-	"fmt"
+	"log"
 	"sports_courses/internal/app/config"
 	"strconv"
+	"sync"
+	"time"
 
 	"github.com/go-redis/redis/v8"
+	"golang.org/x/sync/singleflight"
 )
 
 const servicePrefix = "sports_courses-service."
 
+const (
+	defaultConnectTimeout = 30 * time.Second
+	initialConnectBackoff = 500 * time.Millisecond
+	maxConnectBackoff     = 5 * time.Second
+)
+
 type Client struct {
-	cfg    config.RedisConfig
-	client *redis.Client
+	cfg     config.RedisConfig
+	client  *redis.Client
+	flight  singleflight.Group
+	breaker *breaker
+
+	blacklist *localBlacklist
+
+	mu                   sync.Mutex
+	pendingInvalidations map[string]bool
 }
 
+// New создаёт клиент и ждёт, пока redis ответит на ping, повторяя попытки с
+// растущей паузой в течение cfg.ConnectTimeout. Если redis так и не ответил,
+// клиент всё равно возвращается: сервис стартует в деградированном режиме,
+// а предохранитель не даёт запросам ждать таймаутов, пока redis не поднимется.
 func New(ctx context.Context, cfg config.RedisConfig) (*Client, error) {
 	client := &Client{}
 
@@ -31,14 +51,55 @@ func New(ctx context.Context, cfg config.RedisConfig) (*Client, error) {
 	})
 
 	client.client = redisClient
+	client.breaker = newBreaker(cfg.BreakerThreshold, cfg.BreakerCooldown)
+	client.blacklist = newLocalBlacklist(cfg.BlacklistCacheTTL)
+	client.pendingInvalidations = map[string]bool{}
 
-	if _, err := redisClient.Ping(ctx).Result(); err != nil {
-		return nil, fmt.Errorf("can't ping redis: %w", err)
+	if err := client.waitForPing(ctx); err != nil {
+		log.Println("redis недоступен, сервис запускается без него:", err)
 	}
 
+	redisClient.AddHook(breakerHook{breaker: client.breaker})
+
 	return client, nil
 }
 
+func (c *Client) waitForPing(ctx context.Context) error {
+	timeout := c.cfg.ConnectTimeout
+	if timeout <= 0 {
+		timeout = defaultConnectTimeout
+	}
+
+	deadline := time.Now().Add(timeout)
+	backoff := initialConnectBackoff
+
+	for {
+		err := c.client.Ping(ctx).Err()
+		if err == nil {
+			return nil
+		}
+
+		if time.Now().Add(backoff).After(deadline) {
+			return err
+		}
+
+		log.Printf("redis не отвечает, повтор через %s: %v", backoff, err)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+
+		backoff = min(backoff*2, maxConnectBackoff)
+	}
+}
+
+// Available сообщает, считается ли redis сейчас доступным.
+func (c *Client) Available() bool {
+	return c.breaker.closed()
+}
+
 func (c *Client) Close() error {
 	return c.client.Close()
 }
@@ -0,0 +1,115 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"strings"
+
+	"github.com/go-redis/redis/v8"
+
+	"sports_courses/internal/app/ds"
+)
+
+const (
+	streamChannel = "events"
+	streamLogKey  = "events.log"
+	streamSeqKey  = "events.seq"
+	// streamLogSize - сколько последних событий хранится для переподключения
+	streamLogSize = 1000
+)
+
+// publishStreamScript присваивает событию очередной номер, дописывает его в
+// журнал и рассылает одной операцией, чтобы порядок номеров в журнале совпадал
+// с порядком записи. ARGV[1] - JSON события без начала {"ID":0.
+var publishStreamScript = redis.NewScript(`
+local id = redis.call("INCR", KEYS[1])
+local payload = '{"ID":' .. id .. ARGV[1]
+redis.call("LPUSH", KEYS[2], payload)
+redis.call("LTRIM", KEYS[2], 0, tonumber(ARGV[2]) - 1)
+redis.call("PUBLISH", KEYS[3], payload)
+return id
+`)
+
+// streamIDPrefix - начало JSON события с нулевым номером, вместо которого
+// скрипт подставляет настоящий номер.
+const streamIDPrefix = `{"ID":0`
+
+// PublishStreamEvent присваивает событию очередной номер, дописывает его в
+// журнал последних событий и рассылает всем экземплярам сервиса.
+func (c *Client) PublishStreamEvent(ctx context.Context, event *ds.StreamEvent) error {
+	event.ID = 0
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	keys := []string{servicePrefix + streamSeqKey, servicePrefix + streamLogKey, servicePrefix + streamChannel}
+	id, err := publishStreamScript.Run(ctx, c.client, keys, strings.TrimPrefix(string(data), streamIDPrefix), streamLogSize).Int64()
+	if err != nil {
+		return err
+	}
+
+	event.ID = id
+
+	return nil
+}
+
+// StreamEventsSince возвращает события из журнала с номером больше lastID в
+// порядке их появления. Более старые события, чем хранит журнал, потеряны.
+func (c *Client) StreamEventsSince(ctx context.Context, lastID int64) ([]ds.StreamEvent, error) {
+	values, err := c.client.LRange(ctx, servicePrefix+streamLogKey, 0, streamLogSize-1).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	events := []ds.StreamEvent{}
+	for i := len(values) - 1; i >= 0; i-- {
+		event := ds.StreamEvent{}
+		if err := json.Unmarshal([]byte(values[i]), &event); err != nil {
+			continue
+		}
+
+		if event.ID > lastID {
+			events = append(events, event)
+		}
+	}
+
+	return events, nil
+}
+
+// SubscribeStreamEvents подписывается на события всех экземпляров сервиса и
+// передаёт их в handle, пока не отменён ctx.
+func (c *Client) SubscribeStreamEvents(ctx context.Context, handle func(ds.StreamEvent)) error {
+	return c.subscribe(ctx, servicePrefix+streamChannel, func(payload string) {
+		event := ds.StreamEvent{}
+		if err := json.Unmarshal([]byte(payload), &event); err != nil {
+			log.Println("Не получается разобрать событие из redis:", err)
+			return
+		}
+
+		handle(event)
+	})
+}
+
+// subscribe передаёт в handle сообщения из канала, пока не отменён ctx. При
+// обрыве связи go-redis сам переподключается к каналу.
+func (c *Client) subscribe(ctx context.Context, channel string, handle func(string)) error {
+	pubsub := c.client.Subscribe(ctx, channel)
+	defer pubsub.Close()
+
+	messages := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case message, ok := <-messages:
+			if !ok {
+				return nil
+			}
+
+			handle(message.Payload)
+		}
+	}
+}
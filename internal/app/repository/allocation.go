@@ -0,0 +1,337 @@
+package repository
+
+import (
+	"encoding/json"
+	"errors"
+	"math/rand"
+	"slices"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+
+	"sports_courses/internal/app/ds"
+)
+
+var (
+	ErrPreferencesMismatch = errors.New("в предпочтениях нужно перечислить все группы записи ровно по одному разу")
+	ErrEnrollmentProcessed = errors.New("запись уже обработана")
+)
+
+// SetEnrollmentPreferences расставляет ранги группам записи в порядке group_ids.
+func (r *Repository) SetEnrollmentPreferences(enrollment_id int, group_ids []int) error {
+	tx := r.db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	enrollment := ds.Enrollment{}
+	if err := tx.Select("id", "status").First(&enrollment, "id = ?", enrollment_id).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if enrollment.Status != "Черновик" && enrollment.Status != "Сформирован" {
+		tx.Rollback()
+		return ErrEnrollmentProcessed
+	}
+
+	links := []ds.EnrollmentToGroup{}
+	if err := tx.Where("enrollment_refer = ?", enrollment_id).Find(&links).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if len(links) != len(group_ids) {
+		tx.Rollback()
+		return ErrPreferencesMismatch
+	}
+
+	for _, link := range links {
+		rank := slices.Index(group_ids, link.GroupRefer) + 1
+		if rank == 0 {
+			tx.Rollback()
+			return ErrPreferencesMismatch
+		}
+
+		if err := tx.Model(&ds.EnrollmentToGroup{}).Where("id = ?", link.ID).Update("rank", rank).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit().Error
+}
+
+type allocationCandidate struct {
+	ID        uint
+	UserRefer uuid.UUID
+}
+
+type allocationLink struct {
+	ID              uint
+	EnrollmentRefer uint
+	GroupRefer      uint
+	Rank            int
+}
+
+type allocationGroup struct {
+	ID       uint
+	Title    string
+	Capacity json.Number
+	Enrolled json.Number
+}
+
+// AllocateTerm распределяет места в группах семестра между сформированными
+// записями. Порядок студентов определяет жеребьёвка с зерном Seed: каждый по
+// очереди получает самые желанные группы, в которых ещё остались места. Запись,
+// получившая хотя бы одну группу, завершается, остальные отклоняются. При
+// DryRun ничего не сохраняется.
+//
+// У студента один билет в жеребьёвке, сколько бы сформированных записей у
+// него ни было, и MaxGroupsPerStudent ограничивает группы по всем его записям.
+func (r *Repository) AllocateTerm(request ds.AllocationRequestBody, moderator uuid.UUID) (ds.AllocationReport, error) {
+	if request.Seed == 0 {
+		request.Seed = time.Now().UnixNano()
+	}
+
+	if request.MaxGroupsPerStudent <= 0 {
+		request.MaxGroupsPerStudent = 1
+	}
+
+	report := ds.AllocationReport{
+		TermID:      request.TermID,
+		Seed:        request.Seed,
+		DryRun:      request.DryRun,
+		Groups:      []ds.GroupAllocation{},
+		Enrollments: []ds.EnrollmentAllocation{},
+	}
+
+	if _, err := r.GetTermByID(int(request.TermID)); err != nil {
+		return report, err
+	}
+
+	tx := r.db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := tx.Exec(`SELECT pg_advisory_xact_lock(hashtext('allocation'))`).Error; err != nil {
+		tx.Rollback()
+		return report, err
+	}
+
+	candidates := []allocationCandidate{}
+	err := tx.Model(&ds.Enrollment{}).Select("id, user_refer").
+		Where("term_refer = ? AND status = ?", request.TermID, "Сформирован").
+		Order("id").Scan(&candidates).Error
+	if err != nil {
+		tx.Rollback()
+		return report, err
+	}
+
+	ids := make([]uint, 0, len(candidates))
+	for _, candidate := range candidates {
+		ids = append(ids, candidate.ID)
+	}
+
+	links := []allocationLink{}
+	err = tx.Model(&ds.EnrollmentToGroup{}).Select("id, enrollment_refer, group_refer, rank").
+		Where("enrollment_refer IN ?", ids).
+		Order("enrollment_refer, rank = 0, rank, id").Scan(&links).Error
+	if err != nil {
+		tx.Rollback()
+		return report, err
+	}
+
+	preferences := make(map[uint][]allocationLink, len(candidates))
+	group_ids := []uint{}
+	for _, link := range links {
+		preferences[link.EnrollmentRefer] = append(preferences[link.EnrollmentRefer], link)
+		if !slices.Contains(group_ids, link.GroupRefer) {
+			group_ids = append(group_ids, link.GroupRefer)
+		}
+	}
+
+	groups := []allocationGroup{}
+	err = tx.Model(&ds.Group{}).Select("id, title, capacity, enrolled").Where("id IN ?", group_ids).Order("id").Scan(&groups).Error
+	if err != nil {
+		tx.Rollback()
+		return report, err
+	}
+
+	stats := make(map[uint]*ds.GroupAllocation, len(groups))
+	for _, group := range groups {
+		capacity, _ := strconv.Atoi(string(group.Capacity))
+		enrolled, _ := strconv.Atoi(string(group.Enrolled))
+		report.Groups = append(report.Groups, ds.GroupAllocation{
+			GroupID:   group.ID,
+			Title:     group.Title,
+			Capacity:  capacity,
+			Enrolled:  enrolled,
+			Remaining: max(capacity-enrolled, 0),
+		})
+	}
+	for i := range report.Groups {
+		stats[report.Groups[i].GroupID] = &report.Groups[i]
+	}
+
+	for _, candidate := range candidates {
+		for i, link := range preferences[candidate.ID] {
+			if group, ok := stats[link.GroupRefer]; ok {
+				group.Applicants++
+				if i == 0 {
+					group.FirstChoice++
+				}
+			}
+		}
+	}
+
+	availability := drawLottery(&report, candidates, preferences, request.MaxGroupsPerStudent)
+
+	if request.DryRun {
+		return report, tx.Rollback().Error
+	}
+
+	for link_id, value := range availability {
+		if err := tx.Model(&ds.EnrollmentToGroup{}).Where("id = ?", link_id).Update("availability", value).Error; err != nil {
+			tx.Rollback()
+			return report, err
+		}
+	}
+
+	now := time.Now()
+	for _, result := range report.Enrollments {
+		err := tx.Model(&ds.Enrollment{}).Where("id = ?", result.EnrollmentID).Updates(map[string]interface{}{
+			"status":          result.Status,
+			"moderator_refer": moderator,
+			"date_processed":  now,
+		}).Error
+		if err != nil {
+			tx.Rollback()
+			return report, err
+		}
+
+		if err := emitEnrollmentEvent(tx, result.EnrollmentID, result.Status, ""); err != nil {
+			tx.Rollback()
+			return report, err
+		}
+	}
+
+	for _, group := range report.Groups {
+		if group.Assigned == 0 {
+			continue
+		}
+
+		err := tx.Model(&ds.Group{}).Where("id = ?", group.GroupID).Update("enrolled", strconv.Itoa(group.Enrolled+group.Assigned)).Error
+		if err != nil {
+			tx.Rollback()
+			return report, err
+		}
+	}
+
+	run := ds.AllocationRun{
+		TermRefer: request.TermID,
+		Seed:      request.Seed,
+		RunBy:     moderator,
+		RunAt:     now,
+		Report:    report,
+	}
+	if err := tx.Create(&run).Error; err != nil {
+		tx.Rollback()
+		return report, err
+	}
+
+	report.RunID = run.ID
+
+	return report, tx.Commit().Error
+}
+
+// drawLottery проводит жеребьёвку между студентами записей candidates и
+// раздаёт им места в группах report.Groups. Записи одного студента
+// обрабатываются подряд в порядке id, группы записи - в порядке preferences.
+// Итог зависит только от аргументов и report.Seed, поэтому с тем же зерном
+// повторяется в точности. Возвращает новое значение Availability для каждой связи.
+func drawLottery(report *ds.AllocationReport, candidates []allocationCandidate, preferences map[uint][]allocationLink, maxGroups int) map[uint]string {
+	stats := make(map[uint]*ds.GroupAllocation, len(report.Groups))
+	for i := range report.Groups {
+		stats[report.Groups[i].GroupID] = &report.Groups[i]
+	}
+
+	students := []uuid.UUID{}
+	enrollments := make(map[uuid.UUID][]uint, len(candidates))
+	for _, candidate := range candidates {
+		if _, ok := enrollments[candidate.UserRefer]; !ok {
+			students = append(students, candidate.UserRefer)
+		}
+		enrollments[candidate.UserRefer] = append(enrollments[candidate.UserRefer], candidate.ID)
+	}
+
+	rand.New(rand.NewSource(report.Seed)).Shuffle(len(students), func(i, j int) {
+		students[i], students[j] = students[j], students[i]
+	})
+
+	availability := map[uint]string{}
+	for position, student := range students {
+		assigned := 0
+
+		for _, enrollment_id := range enrollments[student] {
+			result := ds.EnrollmentAllocation{
+				EnrollmentID:    enrollment_id,
+				UserRefer:       student,
+				LotteryPosition: position + 1,
+				Assigned:        []uint{},
+			}
+
+			for _, link := range preferences[enrollment_id] {
+				group, ok := stats[link.GroupRefer]
+				switch {
+				case assigned >= maxGroups:
+					availability[link.ID] = ds.AvailabilityNotNeeded
+				case ok && group.Remaining > 0:
+					availability[link.ID] = ds.AvailabilityAssigned
+					group.Remaining--
+					group.Assigned++
+					assigned++
+					result.Assigned = append(result.Assigned, link.GroupRefer)
+				default:
+					availability[link.ID] = ds.AvailabilityNoSeats
+				}
+			}
+
+			if len(result.Assigned) > 0 {
+				result.Status = "Завершён"
+				report.Completed++
+			} else {
+				result.Status = "Отклонён"
+				report.Rejected++
+			}
+
+			report.Enrollments = append(report.Enrollments, result)
+		}
+	}
+
+	sort.Slice(report.Enrollments, func(i, j int) bool {
+		return report.Enrollments[i].EnrollmentID < report.Enrollments[j].EnrollmentID
+	})
+
+	return availability
+}
+
+func (r *Repository) GetAllocationRun(id int) (*ds.AllocationRun, error) {
+	run := &ds.AllocationRun{}
+
+	err := r.db.First(run, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+
+	run.Report.RunID = run.ID
+
+	return run, nil
+}
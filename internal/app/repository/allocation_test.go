@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"sports_courses/internal/app/ds"
+)
+
+// lotteryFixture - 30 студентов на две группы по 5 мест. У первого студента
+// две сформированные записи.
+func lotteryFixture() (ds.AllocationReport, []allocationCandidate, map[uint][]allocationLink) {
+	report := ds.AllocationReport{
+		Seed: 42,
+		Groups: []ds.GroupAllocation{
+			{GroupID: 1, Capacity: 5, Remaining: 5},
+			{GroupID: 2, Capacity: 5, Remaining: 5},
+		},
+	}
+
+	candidates := []allocationCandidate{}
+	preferences := map[uint][]allocationLink{}
+
+	link_id := uint(0)
+	addEnrollment := func(enrollment_id uint, student uuid.UUID, groups ...uint) {
+		candidates = append(candidates, allocationCandidate{ID: enrollment_id, UserRefer: student})
+		for rank, group_id := range groups {
+			link_id++
+			preferences[enrollment_id] = append(preferences[enrollment_id], allocationLink{
+				ID: link_id, EnrollmentRefer: enrollment_id, GroupRefer: group_id, Rank: rank + 1,
+			})
+		}
+	}
+
+	twice := uuid.MustParse("00000000-0000-0000-0000-000000000001")
+	addEnrollment(1, twice, 1, 2)
+	addEnrollment(2, twice, 2, 1)
+
+	for i := uint(3); i <= 31; i++ {
+		student := uuid.MustParse(fmt.Sprintf("00000000-0000-0000-0000-%012d", i))
+		if i%2 == 0 {
+			addEnrollment(i, student, 1, 2)
+		} else {
+			addEnrollment(i, student, 2, 1)
+		}
+	}
+
+	return report, candidates, preferences
+}
+
+func TestDrawLotteryIsReproducible(t *testing.T) {
+	first, candidates, preferences := lotteryFixture()
+	availability := drawLottery(&first, candidates, preferences, 1)
+
+	for i := 0; i < 10; i++ {
+		again, candidates, preferences := lotteryFixture()
+		if !reflect.DeepEqual(drawLottery(&again, candidates, preferences, 1), availability) || !reflect.DeepEqual(again, first) {
+			t.Fatalf("жеребьёвка с тем же зерном дала другой итог:\n%+v\n%+v", first, again)
+		}
+	}
+
+	other, candidates, preferences := lotteryFixture()
+	other.Seed = 7
+	drawLottery(&other, candidates, preferences, 1)
+	if reflect.DeepEqual(other.Enrollments, first.Enrollments) {
+		t.Fatal("жеребьёвка с другим зерном дала тот же порядок")
+	}
+}
+
+func TestDrawLotteryOneTicketPerStudent(t *testing.T) {
+	report, candidates, preferences := lotteryFixture()
+	drawLottery(&report, candidates, preferences, 1)
+
+	if report.Completed != 10 || report.Rejected != len(candidates)-10 {
+		t.Fatalf("ожидалось 10 завершённых записей, получено %d завершённых и %d отклонённых", report.Completed, report.Rejected)
+	}
+
+	for _, group := range report.Groups {
+		if group.Assigned != group.Capacity || group.Remaining != 0 {
+			t.Fatalf("группа %d заполнена неверно: %+v", group.GroupID, group)
+		}
+	}
+
+	positions := map[uuid.UUID]int{}
+	assigned := map[uuid.UUID]int{}
+	for _, result := range report.Enrollments {
+		if position, ok := positions[result.UserRefer]; ok && position != result.LotteryPosition {
+			t.Fatalf("у записей студента %s разные места в жеребьёвке: %d и %d", result.UserRefer, position, result.LotteryPosition)
+		}
+		positions[result.UserRefer] = result.LotteryPosition
+		assigned[result.UserRefer] += len(result.Assigned)
+	}
+
+	if len(positions) != len(candidates)-1 {
+		t.Fatalf("в жеребьёвке %d билетов, ожидалось %d", len(positions), len(candidates)-1)
+	}
+
+	for student, count := range assigned {
+		if count > 1 {
+			t.Fatalf("студент %s получил %d групп при лимите 1", student, count)
+		}
+	}
+}
@@ -0,0 +1,334 @@
+package repository
+
+import (
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"sports_courses/internal/app/ds"
+)
+
+// maxSessionPeriod ограничивает период, за который создаются занятия одним запросом.
+const maxSessionPeriod = 366 * 24 * time.Hour
+
+var (
+	ErrNoSessionPeriod = errors.New("у группы нет семестра, нужно передать From и To")
+	ErrInvalidPeriod   = errors.New("период должен заканчиваться не раньше, чем начинается, и быть не длиннее года")
+	ErrNotInRoster     = errors.New("студент не зачислен в группу")
+	ErrDuplicateMark   = errors.New("студент отмечен несколько раз")
+)
+
+// termSessions - условие на занятия семестра term_id. Занятия групп без
+// семестра относятся к тому семестру, в даты которого они проходят.
+func termSessions(db *gorm.DB, term_id uint) *gorm.DB {
+	return db.Where(`groups.term_refer = ? OR groups.term_refer IS NULL AND EXISTS (
+		SELECT 1 FROM terms WHERE terms.id = ? AND session_instances.date BETWEEN terms.start_date::date AND terms.end_date::date)`, term_id, term_id)
+}
+
+// groupLinks - связи записей с группой, которые распределение не отклонило.
+func groupLinks(db *gorm.DB, group_id int) *gorm.DB {
+	return db.Table("enrollment_to_groups").
+		Joins("JOIN enrollments ON enrollments.id = enrollment_to_groups.enrollment_refer").
+		Where("enrollment_to_groups.group_refer = ?", group_id).
+		Where("enrollment_to_groups.availability NOT IN ?", []string{ds.AvailabilityNoSeats, ds.AvailabilityNotNeeded})
+}
+
+// groupMembers - подзапрос пользователей, зачисленных в группу: их запись
+// завершена, и распределение не оставило группу без места для них.
+func groupMembers(db *gorm.DB, group_id int) *gorm.DB {
+	return groupLinks(db, group_id).Select("enrollments.user_refer").Where("enrollments.status = ?", "Завершён")
+}
+
+// memberGroups - подзапрос групп, в которые зачислен пользователь.
+func memberGroups(db *gorm.DB, userUUID uuid.UUID) *gorm.DB {
+	return db.Table("enrollment_to_groups").
+		Select("enrollment_to_groups.group_refer").
+		Joins("JOIN enrollments ON enrollments.id = enrollment_to_groups.enrollment_refer").
+		Where("enrollments.user_refer = ? AND enrollments.status = ?", userUUID, "Завершён").
+		Where("enrollment_to_groups.availability NOT IN ?", []string{ds.AvailabilityNoSeats, ds.AvailabilityNotNeeded})
+}
+
+// GenerateGroupSessions создаёт занятия группы по её еженедельному расписанию
+// на каждый подходящий день периода. Без периода берутся даты семестра группы.
+// Уже созданные занятия не дублируются, поэтому вызов можно повторять.
+func (r *Repository) GenerateGroupSessions(group_id int, from, to time.Time) ([]ds.SessionInstance, error) {
+	group := ds.Group{}
+	err := r.db.Preload("Term").Preload("Sessions").First(&group, "id = ?", group_id).Error
+	if err != nil {
+		return nil, err
+	}
+
+	if from.IsZero() || to.IsZero() {
+		if group.Term == nil {
+			return nil, ErrNoSessionPeriod
+		}
+		from, to = group.Term.StartDate, group.Term.EndDate
+	}
+
+	from = time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	to = time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.UTC)
+	if to.Before(from) || to.Sub(from) > maxSessionPeriod {
+		return nil, ErrInvalidPeriod
+	}
+
+	moved := []ds.SessionInstance{}
+	err = r.db.Select("original_date", "original_start_time").
+		Where("group_refer = ? AND original_date BETWEEN ? AND ?", group_id, from.Format("2006-01-02"), to.Format("2006-01-02")).
+		Find(&moved).Error
+	if err != nil {
+		return nil, err
+	}
+
+	instances := []ds.SessionInstance{}
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		weekday := int(day.Weekday())
+		if weekday == 0 {
+			weekday = 7
+		}
+
+		for _, session := range group.Sessions {
+			if session.Weekday != weekday || wasMoved(moved, day, session.StartTime) {
+				continue
+			}
+
+			session_id := session.ID
+			location := session.LocationRefer
+			if location == nil {
+				location = group.LocationRefer
+			}
+
+			instances = append(instances, ds.SessionInstance{
+				GroupRefer:    group_id,
+				SessionRefer:  &session_id,
+				Date:          day,
+				StartTime:     session.StartTime,
+				EndTime:       session.EndTime,
+				LocationRefer: location,
+			})
+		}
+	}
+
+	if len(instances) > 0 {
+		err = r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&instances).Error
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return r.GetGroupSessionInstances(group_id, from, to)
+}
+
+// wasMoved сообщает, было ли занятие, стоявшее в day в startTime, перенесено.
+func wasMoved(moved []ds.SessionInstance, day time.Time, startTime string) bool {
+	for _, instance := range moved {
+		if instance.OriginalStartTime == startTime && instance.OriginalDate.Format("2006-01-02") == day.Format("2006-01-02") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// GetGroupSessionInstances возвращает занятия группы за период. Нулевая
+// граница периода не ограничивает его с этой стороны.
+func (r *Repository) GetGroupSessionInstances(group_id int, from, to time.Time) ([]ds.SessionInstance, error) {
+	instances := []ds.SessionInstance{}
+
+	tx := r.db.Preload("Location").Where("group_refer = ?", group_id)
+	if !from.IsZero() {
+		tx = tx.Where("date >= ?", from.Format("2006-01-02"))
+	}
+	if !to.IsZero() {
+		tx = tx.Where("date <= ?", to.Format("2006-01-02"))
+	}
+
+	err := tx.Order("date, start_time").Find(&instances).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return instances, nil
+}
+
+func (r *Repository) GetSessionInstance(id int) (*ds.SessionInstance, error) {
+	instance := &ds.SessionInstance{}
+
+	err := r.db.Preload("Location").First(instance, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return instance, nil
+}
+
+// IsGroupMember сообщает, зачислен ли пользователь в группу.
+func (r *Repository) IsGroupMember(group_id int, userUUID uuid.UUID) (bool, error) {
+	var count int64
+	err := groupMembers(r.db, group_id).Where("enrollments.user_refer = ?", userUUID).Count(&count).Error
+
+	return count > 0, err
+}
+
+// GetSessionAttendance возвращает отметки на занятии вместе со студентами.
+func (r *Repository) GetSessionAttendance(session_id int) ([]ds.Attendance, error) {
+	marks := []ds.Attendance{}
+
+	err := r.db.Joins("User").Where("session_instance_refer = ?", session_id).Order("\"User\".name").Find(&marks).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return marks, nil
+}
+
+// MarkAttendance сохраняет отметки студентов на занятии, заменяя прежние.
+// Отметить можно только студентов, зачисленных в группу занятия, и каждого
+// не больше одного раза за запрос.
+func (r *Repository) MarkAttendance(session_id uint, marks []ds.AttendanceMark, marker uuid.UUID) error {
+	tx := r.db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	instance := ds.SessionInstance{}
+	if err := tx.First(&instance, "id = ?", session_id).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if instance.Cancelled {
+		tx.Rollback()
+		return ErrSessionCancelled
+	}
+
+	members := []uuid.UUID{}
+	if err := groupMembers(tx, instance.GroupRefer).Scan(&members).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	now := time.Now()
+	rows := make([]ds.Attendance, 0, len(marks))
+	for i, mark := range marks {
+		if !slices.Contains(members, mark.UserUUID) {
+			tx.Rollback()
+			return ErrNotInRoster
+		}
+
+		if slices.ContainsFunc(marks[:i], func(other ds.AttendanceMark) bool { return other.UserUUID == mark.UserUUID }) {
+			tx.Rollback()
+			return ErrDuplicateMark
+		}
+
+		rows = append(rows, ds.Attendance{
+			SessionInstanceRefer: session_id,
+			UserRefer:            mark.UserUUID,
+			Status:               mark.Status,
+			MarkedBy:             marker,
+			MarkedAt:             now,
+		})
+	}
+
+	if len(rows) > 0 {
+		err := tx.Omit("User").Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "session_instance_refer"}, {Name: "user_refer"}},
+			DoUpdates: clause.AssignmentColumns([]string{"status", "marked_by", "marked_at"}),
+		}).Create(&rows).Error
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit().Error
+}
+
+// GetUserAttendance возвращает прошедшие занятия групп, в которые зачислен
+// пользователь, с его отметками. term_id = 0 отключает фильтр по семестру,
+// занятия групп без семестра попадают в семестр по своей дате.
+func (r *Repository) GetUserAttendance(userUUID uuid.UUID, term_id uint) ([]ds.StudentAttendance, error) {
+	result := []ds.StudentAttendance{}
+
+	tx := r.db.Table("session_instances").
+		Select(`session_instances.id AS session_id, groups.id AS group_id, groups.title AS group_title,
+			session_instances.date, session_instances.start_time, session_instances.end_time,
+			session_instances.cancelled, COALESCE(attendances.status, '') AS status`).
+		Joins("JOIN groups ON groups.id = session_instances.group_refer").
+		Joins("LEFT JOIN attendances ON attendances.session_instance_refer = session_instances.id AND attendances.user_refer = ?", userUUID).
+		Where("session_instances.group_refer IN (?)", memberGroups(r.db, userUUID)).
+		Where("session_instances.date <= CURRENT_DATE")
+
+	if term_id != 0 {
+		tx = tx.Where(termSessions(r.db, term_id))
+	}
+
+	err := tx.Order("session_instances.date DESC, session_instances.start_time").Scan(&result).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// GetGroupAttendanceStats считает посещаемость зачисленных в группу студентов
+// по уже прошедшим занятиям. Отменённые занятия не учитываются.
+func (r *Repository) GetGroupAttendanceStats(group_id int) (ds.GroupAttendanceStats, error) {
+	stats := ds.GroupAttendanceStats{GroupID: uint(group_id), Students: []ds.StudentAttendanceStats{}}
+
+	var sessions, held int64
+	err := r.db.Model(&ds.SessionInstance{}).Where("group_refer = ? AND NOT cancelled", group_id).Count(&sessions).Error
+	if err != nil {
+		return stats, err
+	}
+
+	err = r.db.Model(&ds.SessionInstance{}).Where("group_refer = ? AND date <= CURRENT_DATE AND NOT cancelled", group_id).Count(&held).Error
+	if err != nil {
+		return stats, err
+	}
+
+	stats.Sessions, stats.Held = int(sessions), int(held)
+
+	err = r.db.Table("users").
+		Select(`users.uuid AS user_uuid, users.name,
+			COUNT(attendances.id) FILTER (WHERE attendances.status = ?) AS present,
+			COUNT(attendances.id) FILTER (WHERE attendances.status = ?) AS absent,
+			COUNT(attendances.id) FILTER (WHERE attendances.status = ?) AS excused`,
+			ds.AttendancePresent, ds.AttendanceAbsent, ds.AttendanceExcused).
+		Joins(`LEFT JOIN attendances ON attendances.user_refer = users.uuid AND attendances.session_instance_refer IN (?)`,
+			r.db.Model(&ds.SessionInstance{}).Select("id").Where("group_refer = ? AND date <= CURRENT_DATE AND NOT cancelled", group_id)).
+		Where("users.uuid IN (?)", groupMembers(r.db, group_id)).
+		Group("users.uuid, users.name").
+		Order("users.name").
+		Scan(&stats.Students).Error
+	if err != nil {
+		return stats, err
+	}
+
+	var present, counted int
+	for i := range stats.Students {
+		student := &stats.Students[i]
+		student.Unmarked = max(stats.Held-student.Present-student.Absent-student.Excused, 0)
+		student.Rate = attendanceRate(student.Present, stats.Held-student.Excused)
+
+		present += student.Present
+		counted += stats.Held - student.Excused
+	}
+
+	stats.Rate = attendanceRate(present, counted)
+
+	return stats, nil
+}
+
+func attendanceRate(present, total int) float64 {
+	if total <= 0 {
+		return 0
+	}
+
+	return float64(present) / float64(total)
+}
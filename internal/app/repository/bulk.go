@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"time"
+
+	"sports_courses/internal/app/ds"
+)
+
+// GetFormedEnrollmentIDs возвращает сформированные записи, подходящие под фильтр.
+func (r *Repository) GetFormedEnrollmentIDs(filter ds.EnrollmentFilter) ([]uint, error) {
+	ids := []uint{}
+
+	tx := r.db.Model(&ds.Enrollment{}).Where("enrollments.status = ?", "Сформирован")
+	if filter.TermID != 0 {
+		tx = tx.Where("enrollments.term_refer = ?", filter.TermID)
+	}
+
+	if profiles := filterProfiles(r.db, filter); profiles != nil {
+		tx = tx.Where("enrollments.user_refer IN (?)", profiles)
+	}
+
+	err := tx.Order("enrollments.id").Pluck("enrollments.id", &ids).Error
+
+	return ids, err
+}
+
+func (r *Repository) CreateBulkModerationJob(job *ds.BulkModerationJob) error {
+	return r.db.Create(job).Error
+}
+
+func (r *Repository) SaveBulkModerationJob(job *ds.BulkModerationJob) error {
+	return r.db.Save(job).Error
+}
+
+func (r *Repository) GetBulkModerationJob(job_id int) (ds.BulkModerationJob, error) {
+	job := ds.BulkModerationJob{}
+	err := r.db.First(&job, "id = ?", job_id).Error
+
+	return job, err
+}
+
+// FailUnfinishedBulkModerationJobs отмечает прерванными задачи, которые не
+// успели завершиться до остановки сервиса: фоновая обработка после
+// перезапуска не возобновляется. Возвращает число таких задач.
+func (r *Repository) FailUnfinishedBulkModerationJobs() (int64, error) {
+	result := r.db.Model(&ds.BulkModerationJob{}).
+		Where("status IN ?", []string{ds.BulkJobQueued, ds.BulkJobRunning}).
+		Updates(map[string]interface{}{"status": ds.BulkJobFailed, "finished_at": time.Now()})
+
+	return result.RowsAffected, result.Error
+}
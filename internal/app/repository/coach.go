@@ -0,0 +1,165 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"sports_courses/internal/app/ds"
+	"sports_courses/internal/app/role"
+)
+
+var (
+	ErrUserNotFound   = errors.New("пользователь не найден")
+	ErrUserNotStudent = errors.New("тренером можно сделать только пользователя с ролью студента")
+)
+
+var CoachSortColumns = []string{"id", "name"}
+
+// CreateCoach создаёт тренера и, если передан login, в той же транзакции
+// связывает его с аккаунтом пользователя, чтобы при ошибке привязки не
+// оставался тренер без аккаунта.
+func (r *Repository) CreateCoach(coach *ds.Coach, login string) error {
+	tx := r.db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := tx.Omit("User").Create(coach).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if login != "" {
+		if err := linkCoachUser(tx, coach, login); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit().Error
+}
+
+func (r *Repository) EditCoach(coach *ds.Coach) error {
+	return r.db.Model(&ds.Coach{}).Omit("User", "UserRefer").Where("id = ?", coach.ID).Updates(coach).Error
+}
+
+func (r *Repository) GetCoaches(page ds.PageRequest) ([]ds.Coach, ds.PageInfo, error) {
+	coaches := []ds.Coach{}
+
+	if len(page.Sort) == 0 {
+		page.Sort = []ds.SortField{{Column: "name"}}
+	}
+
+	info, err := paginate(r.db.Model(&ds.Coach{}), page, &coaches)
+	if err != nil {
+		return nil, ds.PageInfo{}, err
+	}
+
+	return coaches, info, nil
+}
+
+func (r *Repository) GetCoachByID(id int) (*ds.Coach, error) {
+	coach := &ds.Coach{}
+
+	err := r.db.First(coach, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return coach, nil
+}
+
+func (r *Repository) GetCoachByUser(userUUID uuid.UUID) (*ds.Coach, error) {
+	coach := &ds.Coach{}
+
+	err := r.db.First(coach, "user_refer = ?", userUUID).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return coach, nil
+}
+
+// LinkCoachUser делает пользователя с логином login аккаунтом тренера и
+// выдаёт ему роль тренера. Привязать можно только пользователя с ролью студента.
+func (r *Repository) LinkCoachUser(coach_id int, login string) error {
+	tx := r.db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	coach := ds.Coach{}
+	if err := tx.First(&coach, "id = ?", coach_id).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := linkCoachUser(tx, &coach, login); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+func linkCoachUser(tx *gorm.DB, coach *ds.Coach, login string) error {
+	user := ds.User{}
+	if err := tx.Where("name = ?", login).Limit(1).Find(&user).Error; err != nil {
+		return err
+	}
+
+	if user.UUID == uuid.Nil {
+		return ErrUserNotFound
+	}
+
+	// модератора или администратора нельзя молча понизить до тренера
+	if user.Role != role.User {
+		return ErrUserNotStudent
+	}
+
+	if err := tx.Model(&ds.Coach{}).Where("id = ?", coach.ID).Update("user_refer", user.UUID).Error; err != nil {
+		return err
+	}
+	coach.UserRefer = &user.UUID
+
+	return tx.Model(&ds.User{}).Where("uuid = ?", user.UUID).Update("role", role.Coach).Error
+}
+
+func (r *Repository) GetCoachGroups(coach_id uint) ([]ds.Group, error) {
+	groups := []ds.Group{}
+
+	err := r.db.Where("coach_refer = ?", coach_id).Order("title").Find(&groups).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return groups, nil
+}
+
+// GetGroupRoster возвращает записи со статусом status, в которые входит группа,
+// вместе с записавшимися пользователями. Записи, которым распределение не
+// дало место в этой группе, не возвращаются.
+func (r *Repository) GetGroupRoster(group_id int, status string) ([]ds.Enrollment, error) {
+	enrollments := []ds.Enrollment{}
+
+	err := r.db.Joins("User").
+		Where("enrollments.id IN (?)", groupLinks(r.db, group_id).Select("enrollments.id")).
+		Where("enrollments.status = ?", status).
+		Order("enrollments.date_created").
+		Find(&enrollments).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return enrollments, nil
+}
+
+func (r *Repository) SetGroupDescription(group_id int, description string) error {
+	return r.db.Model(&ds.Group{}).Where("id = ?", group_id).Update("description", description).Error
+}
@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"sports_courses/internal/app/ds"
+)
+
+var ErrInvalidRejection = errors.New("для отклонения нужно указать код причины и комментарий")
+
+// CheckRejection проверяет, что у отклонения есть известный код причины и комментарий.
+func CheckRejection(rejection ds.RejectionRequestBody) error {
+	if _, ok := ds.RejectionReasons[rejection.ReasonCode]; !ok || strings.TrimSpace(rejection.Comment) == "" {
+		return ErrInvalidRejection
+	}
+
+	return nil
+}
+
+// rejectionReason - причина отклонения в том виде, в каком её увидит студент.
+func rejectionReason(rejection ds.RejectionRequestBody) string {
+	return ds.RejectionReasons[rejection.ReasonCode] + ". " + strings.TrimSpace(rejection.Comment)
+}
+
+// GetEnrollmentComments возвращает обсуждение записи от старых сообщений к
+// новым. Внутренние заметки попадают в ответ, только если internal = true.
+func (r *Repository) GetEnrollmentComments(enrollment_id uint, internal bool) ([]ds.EnrollmentComment, error) {
+	comments := []ds.EnrollmentComment{}
+
+	tx := r.db.Where("enrollment_refer = ?", enrollment_id)
+	if !internal {
+		tx = tx.Where("NOT internal")
+	}
+
+	err := tx.Order("created_at, id").Find(&comments).Error
+
+	return comments, err
+}
+
+// AddEnrollmentComment сохраняет сообщение и уведомляет владельца записи и её
+// модератора, кроме самого автора. О внутренней заметке студент не узнаёт.
+func (r *Repository) AddEnrollmentComment(comment *ds.EnrollmentComment) error {
+	tx := r.db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	enrollment := ds.Enrollment{}
+	if err := tx.Select("id", "user_refer", "moderator_refer").First(&enrollment, "id = ?", comment.EnrollmentRefer).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	author := ds.User{}
+	if err := tx.Select("name").Where("uuid = ?", comment.AuthorRefer).Limit(1).Find(&author).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	comment.AuthorName = author.Name
+	comment.CreatedAt = time.Now()
+
+	if err := tx.Create(comment).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	recipients := []*uuid.UUID{enrollment.ModeratorRefer}
+	if !comment.Internal {
+		recipients = append(recipients, enrollment.UserRefer)
+	}
+
+	events := []ds.DomainEvent{}
+	for _, recipient := range recipients {
+		if recipient == nil || *recipient == comment.AuthorRefer {
+			continue
+		}
+
+		events = append(events, ds.DomainEvent{
+			Type:      ds.EventEnrollmentComment,
+			UserRefer: *recipient,
+			Payload: map[string]interface{}{
+				"EnrollmentID": enrollment.ID,
+				"CommentID":    comment.ID,
+				"AuthorName":   comment.AuthorName,
+				"Body":         comment.Body,
+			},
+			CreatedAt: comment.CreatedAt,
+		})
+	}
+
+	if err := recordEvents(tx, events); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"errors"
+
+	"sports_courses/internal/app/ds"
+)
+
+var ErrCourseInUse = errors.New("курс используется группами")
+
+var CourseSortColumns = []string{"id", "name", "sport_type"}
+
+// ActiveGroupStatus - статус группы, в которую сейчас идёт запись.
+const ActiveGroupStatus = "Действует"
+
+func (r *Repository) CreateCourse(course *ds.Course) error {
+	return r.db.Create(course).Error
+}
+
+func (r *Repository) EditCourse(course *ds.Course) error {
+	return r.db.Model(&ds.Course{}).Omit("ImageName").Where("id = ?", course.ID).Updates(course).Error
+}
+
+func (r *Repository) GetCourseByID(id int) (*ds.Course, error) {
+	course := &ds.Course{}
+
+	err := r.db.First(course, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return course, nil
+}
+
+// GetCourses возвращает курсы с количеством действующих групп и свободных мест в них.
+func (r *Repository) GetCourses(page ds.PageRequest) ([]ds.CourseSummary, ds.PageInfo, error) {
+	courses := []ds.CourseSummary{}
+
+	if len(page.Sort) == 0 {
+		page.Sort = []ds.SortField{{Column: "name"}}
+	}
+
+	tx := r.db.Model(&ds.Course{}).
+		Select("courses.*, "+
+			"count(groups.id) AS active_groups, "+
+			"coalesce(sum(greatest(nullif(groups.capacity, '')::numeric - coalesce(nullif(groups.enrolled, ''), '0')::numeric, 0)), 0) AS free_seats").
+		Joins("LEFT JOIN groups ON groups.course_refer = courses.id AND groups.status = ?", ActiveGroupStatus).
+		Group("courses.id")
+
+	info, err := paginate(tx, page, &courses)
+	if err != nil {
+		return nil, ds.PageInfo{}, err
+	}
+
+	return courses, info, nil
+}
+
+func (r *Repository) SetCourseImage(id int, image string) error {
+	return r.db.Model(&ds.Course{}).Where("id = ?", id).Update("image_name", image).Error
+}
+
+// DeleteCourse удаляет курс, если на него не ссылается ни одна группа.
+func (r *Repository) DeleteCourse(id int) (*ds.Course, error) {
+	course, err := r.GetCourseByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var groups int64
+	err = r.db.Model(&ds.Group{}).Where("course_refer = ?", id).Count(&groups).Error
+	if err != nil {
+		return nil, err
+	}
+
+	if groups > 0 {
+		return nil, ErrCourseInUse
+	}
+
+	return course, r.db.Delete(&ds.Course{}, id).Error
+}
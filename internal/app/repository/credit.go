@@ -0,0 +1,234 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"sports_courses/internal/app/ds"
+)
+
+var ErrInvalidCreditRequirement = errors.New("требование к зачёту задано некорректно")
+
+var CreditRequirementSortColumns = []string{"id", "term_refer", "year"}
+
+func validateCreditRequirement(requirement *ds.CreditRequirement) error {
+	if requirement.TermRefer == 0 {
+		return fmt.Errorf("%w: нужно указать семестр", ErrInvalidCreditRequirement)
+	}
+
+	if requirement.Year < 0 {
+		return fmt.Errorf("%w: курс не может быть отрицательным", ErrInvalidCreditRequirement)
+	}
+
+	if requirement.RequiredHours <= 0 {
+		return fmt.Errorf("%w: RequiredHours должен быть больше нуля", ErrInvalidCreditRequirement)
+	}
+
+	if requirement.MinAttendanceRate < 0 || requirement.MinAttendanceRate > 1 {
+		return fmt.Errorf("%w: MinAttendanceRate должен быть от 0 до 1", ErrInvalidCreditRequirement)
+	}
+
+	return nil
+}
+
+func (r *Repository) CreateCreditRequirement(requirement *ds.CreditRequirement) error {
+	if err := validateCreditRequirement(requirement); err != nil {
+		return err
+	}
+
+	return r.db.Create(requirement).Error
+}
+
+func (r *Repository) EditCreditRequirement(requirement *ds.CreditRequirement) error {
+	if err := validateCreditRequirement(requirement); err != nil {
+		return err
+	}
+
+	if err := r.db.First(&ds.CreditRequirement{}, "id = ?", requirement.ID).Error; err != nil {
+		return err
+	}
+
+	return r.db.Save(requirement).Error
+}
+
+// GetCreditRequirements возвращает требования к зачёту, term_id = 0 - по всем семестрам.
+func (r *Repository) GetCreditRequirements(term_id uint, page ds.PageRequest) ([]ds.CreditRequirement, ds.PageInfo, error) {
+	requirements := []ds.CreditRequirement{}
+
+	if len(page.Sort) == 0 {
+		page.Sort = []ds.SortField{{Column: "term_refer"}, {Column: "year"}}
+	}
+
+	tx := r.db.Model(&ds.CreditRequirement{})
+	if term_id != 0 {
+		tx = tx.Where("term_refer = ?", term_id)
+	}
+
+	info, err := paginate(tx, page, &requirements)
+	if err != nil {
+		return nil, ds.PageInfo{}, err
+	}
+
+	return requirements, info, nil
+}
+
+func (r *Repository) DeleteCreditRequirement(id int) error {
+	return r.db.Delete(&ds.CreditRequirement{}, id).Error
+}
+
+type creditStats struct {
+	UserUUID      uuid.UUID
+	Held          int
+	Present       int
+	Excused       int
+	CreditedHours float64
+}
+
+// GetStudentCredits считает зачтённые часы студентов за семестр: часы
+// прошедших и не отменённых занятий групп, в которые студент зачислен, на
+// которых он отмечен присутствующим. Занятия групп без семестра учитываются,
+// если проходят в даты семестра, как и в посещаемости студента.
+func (r *Repository) GetStudentCredits(term_id uint, users []uuid.UUID) ([]ds.StudentCredit, error) {
+	term, err := r.GetTermByID(int(term_id))
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]ds.StudentCredit, 0, len(users))
+	if len(users) == 0 {
+		return result, nil
+	}
+
+	profiles, err := r.loadProfiles(users)
+	if err != nil {
+		return nil, err
+	}
+
+	names := []ds.User{}
+	if err := r.db.Select("uuid", "name").Where("uuid IN ?", users).Find(&names).Error; err != nil {
+		return nil, err
+	}
+
+	requirements := []ds.CreditRequirement{}
+	if err := r.db.Where("term_refer = ?", term_id).Find(&requirements).Error; err != nil {
+		return nil, err
+	}
+
+	members := r.db.Table("enrollment_to_groups").
+		Distinct("enrollments.user_refer", "enrollment_to_groups.group_refer").
+		Joins("JOIN enrollments ON enrollments.id = enrollment_to_groups.enrollment_refer").
+		Where("enrollments.user_refer IN ? AND enrollments.status = ?", users, "Завершён").
+		Where("enrollment_to_groups.availability NOT IN ?", []string{ds.AvailabilityNoSeats, ds.AvailabilityNotNeeded})
+
+	stats := []creditStats{}
+	err = r.db.Table("session_instances").
+		Select(`members.user_refer AS user_uuid,
+			COUNT(session_instances.id) AS held,
+			COUNT(attendances.id) FILTER (WHERE attendances.status = ?) AS present,
+			COUNT(attendances.id) FILTER (WHERE attendances.status = ?) AS excused,
+			COALESCE(SUM(EXTRACT(EPOCH FROM session_instances.end_time::time - session_instances.start_time::time) / 3600)
+				FILTER (WHERE attendances.status = ?), 0) AS credited_hours`,
+			ds.AttendancePresent, ds.AttendanceExcused, ds.AttendancePresent).
+		Joins("JOIN groups ON groups.id = session_instances.group_refer").
+		Joins("JOIN (?) AS members ON members.group_refer = session_instances.group_refer", members).
+		Joins("LEFT JOIN attendances ON attendances.session_instance_refer = session_instances.id AND attendances.user_refer = members.user_refer").
+		Where("session_instances.date <= CURRENT_DATE AND NOT session_instances.cancelled").
+		Where(termSessions(r.db, term_id)).
+		Group("members.user_refer").
+		Scan(&stats).Error
+	if err != nil {
+		return nil, err
+	}
+
+	byUser := make(map[uuid.UUID]creditStats, len(stats))
+	for _, stat := range stats {
+		byUser[stat.UserUUID] = stat
+	}
+
+	byName := make(map[uuid.UUID]string, len(names))
+	for _, user := range names {
+		byName[user.UUID] = user.Name
+	}
+
+	now := time.Now()
+	for _, userUUID := range users {
+		stat := byUser[userUUID]
+		credit := ds.StudentCredit{
+			UserUUID:       userUUID,
+			Name:           byName[userUUID],
+			TermID:         term_id,
+			CreditedHours:  stat.CreditedHours,
+			Held:           stat.Held,
+			Present:        stat.Present,
+			Excused:        stat.Excused,
+			AttendanceRate: attendanceRate(stat.Present, stat.Held-stat.Excused),
+		}
+
+		if profile := profiles[userUUID]; profile != nil {
+			credit.FullName = profile.FullName
+			credit.StudyGroup = profile.StudyGroup
+			credit.Year = profile.Year
+		}
+
+		requirement := creditRequirementFor(requirements, credit.Year)
+		switch {
+		case requirement == nil:
+			credit.Status = ds.CreditNoRequirement
+		default:
+			credit.RequirementID = &requirement.ID
+			credit.RequiredHours = requirement.RequiredHours
+			credit.MinAttendanceRate = requirement.MinAttendanceRate
+
+			if credit.CreditedHours >= requirement.RequiredHours && credit.AttendanceRate >= requirement.MinAttendanceRate {
+				credit.Status = ds.CreditPassed
+			} else if now.Before(term.EndDate) {
+				credit.Status = ds.CreditInProgress
+			} else {
+				credit.Status = ds.CreditFailed
+			}
+		}
+
+		result = append(result, credit)
+	}
+
+	return result, nil
+}
+
+// GetStudyGroupCredits считает зачёт для всех студентов учебной группы.
+// Как и в фильтре записей, ИУ5 находит все группы кафедры.
+func (r *Repository) GetStudyGroupCredits(term_id uint, study_group string) ([]ds.StudentCredit, error) {
+	users := []uuid.UUID{}
+
+	err := r.db.Model(&ds.StudentProfile{}).
+		Where("study_group ILIKE ?", escapeLike(study_group)+"%").
+		Order("study_group, full_name").
+		Pluck("user_refer", &users).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return r.GetStudentCredits(term_id, users)
+}
+
+// creditRequirementFor выбирает требование для курса year, а если для курса
+// своего требования нет - общее требование семестра.
+func creditRequirementFor(requirements []ds.CreditRequirement, year int) *ds.CreditRequirement {
+	var common *ds.CreditRequirement
+
+	for i := range requirements {
+		switch requirements[i].Year {
+		case year:
+			if year != 0 {
+				return &requirements[i]
+			}
+			common = &requirements[i]
+		case 0:
+			common = &requirements[i]
+		}
+	}
+
+	return common
+}
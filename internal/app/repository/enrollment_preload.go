@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"sports_courses/internal/app/ds"
+)
+
+// preloadEnrollments присоединяет к запросу пользователя и модератора через
+// LEFT JOIN, поэтому они загружаются тем же запросом, что и сами записи.
+func preloadEnrollments(tx *gorm.DB, expand ds.EnrollmentExpand) *gorm.DB {
+	if expand.User {
+		tx = tx.Joins("User")
+	}
+
+	if expand.Moderator {
+		tx = tx.Joins("Moderator")
+	}
+
+	return tx
+}
+
+// loadEnrollmentGroups одним запросом загружает группы всех переданных записей.
+func (r *Repository) loadEnrollmentGroups(enrollments []ds.Enrollment) error {
+	if len(enrollments) == 0 {
+		return nil
+	}
+
+	ids := make([]uint, 0, len(enrollments))
+	for _, enrollment := range enrollments {
+		ids = append(ids, enrollment.ID)
+	}
+
+	links := []ds.EnrollmentToGroup{}
+	err := r.db.Joins("Group").Where("enrollment_to_groups.enrollment_refer IN ?", ids).Order("enrollment_to_groups.id").Find(&links).Error
+	if err != nil {
+		return err
+	}
+
+	groups := make(map[int][]ds.Group, len(enrollments))
+	for _, link := range links {
+		groups[link.EnrollmentRefer] = append(groups[link.EnrollmentRefer], link.Group)
+	}
+
+	for i := range enrollments {
+		enrollments[i].Groups = groups[int(enrollments[i].ID)]
+		if enrollments[i].Groups == nil {
+			enrollments[i].Groups = []ds.Group{}
+		}
+	}
+
+	return nil
+}
+
+// loadEnrollmentProfiles одним запросом загружает профили студентов, чьи
+// записи переданы. Пользователь записи к этому моменту уже должен быть загружен.
+func (r *Repository) loadEnrollmentProfiles(enrollments []ds.Enrollment) error {
+	if len(enrollments) == 0 {
+		return nil
+	}
+
+	users := make([]uuid.UUID, 0, len(enrollments))
+	for _, enrollment := range enrollments {
+		if enrollment.UserRefer != nil {
+			users = append(users, *enrollment.UserRefer)
+		}
+	}
+
+	profiles, err := r.loadProfiles(users)
+	if err != nil {
+		return err
+	}
+
+	for i := range enrollments {
+		enrollments[i].User.Profile = profiles[enrollments[i].User.UUID]
+	}
+
+	return nil
+}
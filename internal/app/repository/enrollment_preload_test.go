@@ -0,0 +1,151 @@
+package repository
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"sports_courses/internal/app/ds"
+	"sports_courses/internal/app/role"
+)
+
+// enrollmentListQueries - сколько запросов нужно на страницу записей с
+// пользователем, модератором, профилями и группами: count, сами записи,
+// группы и профили.
+const enrollmentListQueries = 4
+
+// newMockRepository возвращает репозиторий поверх sqlmock и счётчик
+// выполненных запросов.
+func newMockRepository(b *testing.B) (*Repository, sqlmock.Sqlmock, *int) {
+	b.Helper()
+
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() { conn.Close() })
+
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: conn}), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	queries := 0
+	count := func(*gorm.DB) { queries++ }
+	if err := db.Callback().Query().Before("gorm:query").Register("test:count_queries", count); err != nil {
+		b.Fatal(err)
+	}
+	if err := db.Callback().Row().Before("gorm:row").Register("test:count_rows", count); err != nil {
+		b.Fatal(err)
+	}
+
+	return &Repository{db: db}, mock, &queries
+}
+
+// expectEnrollmentPage готовит ответы базы на страницу из size записей, у
+// каждой из которых свой студент, модератор, профиль и две группы.
+func expectEnrollmentPage(mock sqlmock.Sqlmock, size int) {
+	mock.ExpectQuery(`SELECT count\(\*\) FROM "enrollments"`).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(size))
+
+	enrollments := sqlmock.NewRows([]string{"id", "user_refer", "moderator_refer", "status",
+		"User__uuid", "User__name", "User__role", "Moderator__uuid", "Moderator__name", "Moderator__role"})
+	groups := sqlmock.NewRows([]string{"id", "enrollment_refer", "group_refer", "Group__id", "Group__title"})
+	profiles := sqlmock.NewRows([]string{"user_refer", "study_group"})
+
+	for i := 1; i <= size; i++ {
+		user, moderator := uuid.New(), uuid.New()
+		enrollments.AddRow(i, user, moderator, "Сформирован", user, fmt.Sprint("student ", i), role.User, moderator, "moderator", role.Moderator)
+		groups.AddRow(2*i-1, i, 2*i-1, 2*i-1, fmt.Sprint("group ", 2*i-1))
+		groups.AddRow(2*i, i, 2*i, 2*i, fmt.Sprint("group ", 2*i))
+		profiles.AddRow(user, "ИУ5-31Б")
+	}
+
+	mock.ExpectQuery(`FROM "enrollments" LEFT JOIN "users" "User"`).WillReturnRows(enrollments)
+	mock.ExpectQuery(`FROM "enrollment_to_groups" LEFT JOIN "groups" "Group"`).WillReturnRows(groups)
+	mock.ExpectQuery(`FROM "student_profiles"`).WillReturnRows(profiles)
+}
+
+// BenchmarkGetEnrollments проверяет, что число запросов на страницу записей
+// со всеми связанными данными не зависит от размера страницы.
+func BenchmarkGetEnrollments(b *testing.B) {
+	expand := ds.EnrollmentExpand{Groups: true, User: true, Moderator: true}
+
+	for _, size := range []int{1, 10, 100, 1000} {
+		b.Run(fmt.Sprintf("enrollments=%d", size), func(b *testing.B) {
+			repo, mock, queries := newMockRepository(b)
+			page := ds.PageRequest{Limit: size}
+
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				expectEnrollmentPage(mock, size)
+				*queries = 0
+				b.StartTimer()
+
+				enrollments, _, err := repo.GetEnrollments(ds.EnrollmentFilter{}, role.Moderator, uuid.Nil, page, expand)
+				if err != nil {
+					b.Fatal(err)
+				}
+
+				b.StopTimer()
+				if len(enrollments) != size || len(enrollments[size-1].Groups) != 2 || enrollments[size-1].User.Profile == nil {
+					b.Fatalf("связанные данные загружены не полностью: %+v", enrollments[size-1])
+				}
+				if *queries != enrollmentListQueries {
+					b.Fatalf("на %d записей выполнено %d запросов, ожидалось %d", size, *queries, enrollmentListQueries)
+				}
+				if err := mock.ExpectationsWereMet(); err != nil {
+					b.Fatal(err)
+				}
+				b.StartTimer()
+			}
+
+			b.ReportMetric(float64(*queries), "queries/op")
+		})
+	}
+}
+
+// BenchmarkFindEnrollment проверяет, что одна запись со всеми связанными
+// данными загружается тем же числом запросов, что и страница записей, без count.
+func BenchmarkFindEnrollment(b *testing.B) {
+	expand := ds.EnrollmentExpand{Groups: true, User: true, Moderator: true}
+	repo, mock, queries := newMockRepository(b)
+
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		user, moderator := uuid.New(), uuid.New()
+		mock.ExpectQuery(`FROM "enrollments" LEFT JOIN "users" "User"`).WillReturnRows(
+			sqlmock.NewRows([]string{"id", "user_refer", "moderator_refer", "User__uuid", "Moderator__uuid", "Moderator__name"}).
+				AddRow(1, user, moderator, user, moderator, "moderator"))
+		mock.ExpectQuery(`FROM "enrollment_to_groups" LEFT JOIN "groups" "Group"`).WillReturnRows(
+			sqlmock.NewRows([]string{"id", "enrollment_refer", "group_refer", "Group__id"}).AddRow(1, 1, 1, 1))
+		mock.ExpectQuery(`FROM "student_profiles"`).WillReturnRows(
+			sqlmock.NewRows([]string{"user_refer"}).AddRow(user))
+		*queries = 0
+		b.StartTimer()
+
+		enrollment, err := repo.FindEnrollment(&ds.Enrollment{ID: 1}, expand)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		b.StopTimer()
+		if enrollment.Moderator.Name != "moderator" || enrollment.User.UUID != user {
+			b.Fatalf("пользователь и модератор загружены неверно: %+v", enrollment)
+		}
+		if *queries != enrollmentListQueries-1 {
+			b.Fatalf("выполнено %d запросов, ожидалось %d", *queries, enrollmentListQueries-1)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			b.Fatal(err)
+		}
+		b.StartTimer()
+	}
+
+	b.ReportMetric(float64(*queries), "queries/op")
+}
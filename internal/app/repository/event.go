@@ -0,0 +1,252 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"sports_courses/internal/app/ds"
+)
+
+// emitGroupEvent пишет событие каждому студенту, зачисленному в группу.
+func emitGroupEvent(tx *gorm.DB, group_id int, eventType string, payload map[string]interface{}) error {
+	members := []uuid.UUID{}
+	if err := groupMembers(tx, group_id).Distinct().Pluck("enrollments.user_refer", &members).Error; err != nil {
+		return err
+	}
+
+	now := time.Now()
+	events := make([]ds.DomainEvent, 0, len(members))
+	for _, member := range members {
+		events = append(events, ds.DomainEvent{
+			Type:      eventType,
+			UserRefer: member,
+			Payload:   payload,
+			CreatedAt: now,
+		})
+	}
+
+	return recordEvents(tx, events)
+}
+
+// emitEnrollmentEvent сообщает владельцу записи, что её статус изменился.
+// Причина reason попадает в уведомление, если она указана.
+func emitEnrollmentEvent(tx *gorm.DB, enrollment_id uint, status string, reason string) error {
+	enrollment := ds.Enrollment{}
+	if err := tx.Select("id", "user_refer").First(&enrollment, "id = ?", enrollment_id).Error; err != nil {
+		return err
+	}
+
+	if enrollment.UserRefer == nil {
+		return nil
+	}
+
+	payload := map[string]interface{}{
+		"EnrollmentID": enrollment.ID,
+		"Status":       status,
+	}
+	if reason != "" {
+		payload["Reason"] = reason
+	}
+
+	return recordEvents(tx, []ds.DomainEvent{{
+		Type:      ds.EventEnrollmentStatusChanged,
+		UserRefer: *enrollment.UserRefer,
+		Payload:   payload,
+		CreatedAt: time.Now(),
+	}})
+}
+
+// emitWaitlistEvent сообщает владельцу записи, что его зачислили в группу, в
+// которой при распределении не хватило мест.
+func emitWaitlistEvent(tx *gorm.DB, link ds.EnrollmentToGroup) error {
+	enrollment := ds.Enrollment{}
+	if err := tx.Select("id", "user_refer").First(&enrollment, "id = ?", link.EnrollmentRefer).Error; err != nil {
+		return err
+	}
+
+	if enrollment.UserRefer == nil {
+		return nil
+	}
+
+	group := ds.Group{}
+	if err := tx.Select("id", "title").First(&group, "id = ?", link.GroupRefer).Error; err != nil {
+		return err
+	}
+
+	return recordEvents(tx, []ds.DomainEvent{{
+		Type:      ds.EventWaitlistPromoted,
+		UserRefer: *enrollment.UserRefer,
+		Payload: map[string]interface{}{
+			"EnrollmentID": enrollment.ID,
+			"GroupID":      group.ID,
+			"GroupTitle":   group.Title,
+		},
+		CreatedAt: time.Now(),
+	}})
+}
+
+// recordEvents сохраняет события и создаёт по ним уведомления тем
+// пользователям, которые не отключили уведомления этого типа.
+func recordEvents(tx *gorm.DB, events []ds.DomainEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	if err := tx.Create(&events).Error; err != nil {
+		return err
+	}
+
+	users := make([]uuid.UUID, 0, len(events))
+	for _, event := range events {
+		users = append(users, event.UserRefer)
+	}
+
+	disabled := []ds.NotificationPreference{}
+	if err := tx.Where("user_refer IN ? AND NOT enabled", users).Find(&disabled).Error; err != nil {
+		return err
+	}
+
+	muted := make(map[string]bool, len(disabled))
+	for _, preference := range disabled {
+		muted[preference.UserRefer.String()+preference.Type] = true
+	}
+
+	notifications := make([]ds.Notification, 0, len(events))
+	for _, event := range events {
+		if muted[event.UserRefer.String()+event.Type] {
+			continue
+		}
+
+		title, body := describeEvent(event)
+		notifications = append(notifications, ds.Notification{
+			UserRefer:  event.UserRefer,
+			EventRefer: event.ID,
+			Type:       event.Type,
+			Title:      title,
+			Body:       body,
+			Payload:    event.Payload,
+			CreatedAt:  event.CreatedAt,
+		})
+	}
+
+	if len(notifications) == 0 {
+		return nil
+	}
+
+	return tx.Create(&notifications).Error
+}
+
+// describeEvent составляет заголовок и текст уведомления о событии.
+func describeEvent(event ds.DomainEvent) (string, string) {
+	payload := event.Payload
+
+	switch event.Type {
+	case ds.EventSessionCancelled:
+		return "Занятие отменено", fmt.Sprintf("Занятие группы «%v» %s в %v отменено. Причина: %v",
+			payload["GroupTitle"], eventDate(payload["Date"]), payload["StartTime"], payload["Reason"])
+	case ds.EventSessionMoved:
+		return "Занятие перенесено", fmt.Sprintf("Занятие группы «%v» перенесено с %s %v на %s %v. Причина: %v",
+			payload["GroupTitle"], eventDate(payload["PreviousDate"]), payload["PreviousStartTime"],
+			eventDate(payload["Date"]), payload["StartTime"], payload["Reason"])
+	case ds.EventEnrollmentStatusChanged:
+		switch payload["Status"] {
+		case "Завершён":
+			return "Запись одобрена", fmt.Sprintf("Запись №%v одобрена", payload["EnrollmentID"])
+		case "Отклонён":
+			if reason, ok := payload["Reason"]; ok {
+				return "Запись отклонена", fmt.Sprintf("Запись №%v отклонена. Причина: %v", payload["EnrollmentID"], reason)
+			}
+			return "Запись отклонена", fmt.Sprintf("Запись №%v отклонена", payload["EnrollmentID"])
+		default:
+			return "Статус записи изменён", fmt.Sprintf("Запись №%v: статус «%v»", payload["EnrollmentID"], payload["Status"])
+		}
+	case ds.EventEnrollmentComment:
+		return "Новое сообщение по записи", fmt.Sprintf("%v, запись №%v: %v", payload["AuthorName"], payload["EnrollmentID"], payload["Body"])
+	case ds.EventWaitlistPromoted:
+		return "Освободилось место", fmt.Sprintf("Вы зачислены в группу «%v» из листа ожидания, запись №%v", payload["GroupTitle"], payload["EnrollmentID"])
+	}
+
+	return event.Type, ""
+}
+
+// eventDate переводит дату из события в формат ДД.ММ.ГГГГ.
+func eventDate(value interface{}) string {
+	date, err := time.Parse("2006-01-02", fmt.Sprint(value))
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+
+	return date.Format("02.01.2006")
+}
+
+// relayBatchSize - сколько событий публикуется за один проход.
+const relayBatchSize = 500
+
+// RelayEvents передаёт publish ещё не опубликованные события вместе с
+// созданными по ним уведомлениями и отмечает их опубликованными. Одновременно
+// события публикует только один экземпляр сервиса. Если publish вернул
+// ошибку, события останутся неопубликованными до следующего прохода, поэтому
+// клиент может изредка получить событие дважды. Возвращает число событий.
+func (r *Repository) RelayEvents(publish func(ds.DomainEvent, *ds.Notification) error) (int, error) {
+	tx := r.db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	var locked bool
+	if err := tx.Raw(`SELECT pg_try_advisory_xact_lock(hashtext('event_relay'))`).Scan(&locked).Error; err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	if !locked {
+		tx.Rollback()
+		return 0, nil
+	}
+
+	events := []ds.DomainEvent{}
+	if err := tx.Where("NOT published").Order("id").Limit(relayBatchSize).Find(&events).Error; err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	if len(events) == 0 {
+		tx.Rollback()
+		return 0, nil
+	}
+
+	ids := make([]uint, 0, len(events))
+	for _, event := range events {
+		ids = append(ids, event.ID)
+	}
+
+	notifications := []ds.Notification{}
+	if err := tx.Where("event_refer IN ?", ids).Find(&notifications).Error; err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	byEvent := make(map[uint]*ds.Notification, len(notifications))
+	for i := range notifications {
+		byEvent[notifications[i].EventRefer] = &notifications[i]
+	}
+
+	for _, event := range events {
+		if err := publish(event, byEvent[event.ID]); err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+	}
+
+	if err := tx.Model(&ds.DomainEvent{}).Where("id IN ?", ids).Update("published", true).Error; err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	return len(events), tx.Commit().Error
+}
@@ -0,0 +1,215 @@
+package repository
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+
+	"sports_courses/internal/app/ds"
+)
+
+var ErrImagesMismatch = errors.New("список картинок не совпадает с галереей группы")
+
+func (r *Repository) GetGroupImages(group_id int) ([]ds.GroupImage, error) {
+	images := []ds.GroupImage{}
+
+	err := r.db.Where("group_refer = ?", group_id).Order("position").Find(&images).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return images, nil
+}
+
+func (r *Repository) GetGroupImage(id int) (*ds.GroupImage, error) {
+	image := &ds.GroupImage{}
+
+	err := r.db.First(image, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return image, nil
+}
+
+// AddGroupImage добавляет картинку в конец галереи группы. Первая картинка
+// группы, как и картинка с флагом IsCover, становится обложкой.
+func (r *Repository) AddGroupImage(image *ds.GroupImage) error {
+	tx := r.db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	var count int64
+	if err := tx.Model(&ds.GroupImage{}).Where("group_refer = ?", image.GroupRefer).Count(&count).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	var position int
+	if err := tx.Model(&ds.GroupImage{}).Where("group_refer = ?", image.GroupRefer).Select("coalesce(max(position), -1) + 1").Scan(&position).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	image.Position = position
+	if count == 0 {
+		image.IsCover = true
+	}
+
+	if image.IsCover {
+		if err := tx.Model(&ds.GroupImage{}).Where("group_refer = ?", image.GroupRefer).Update("is_cover", false).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	if err := tx.Omit("Group").Create(image).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if image.IsCover {
+		if err := setGroupCover(tx, image.GroupRefer, image.ImageName); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit().Error
+}
+
+func (r *Repository) EditGroupImageCaption(id int, caption string) error {
+	result := r.db.Model(&ds.GroupImage{}).Where("id = ?", id).Update("caption", caption)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// ReorderGroupImages выставляет порядок картинок галереи. В image_ids должны
+// быть перечислены все картинки группы, каждая ровно один раз.
+func (r *Repository) ReorderGroupImages(group_id int, image_ids []int) error {
+	tx := r.db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	var existing []int
+	if err := tx.Model(&ds.GroupImage{}).Where("group_refer = ?", group_id).Pluck("id", &existing).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if len(existing) != len(image_ids) {
+		tx.Rollback()
+		return ErrImagesMismatch
+	}
+
+	known := make(map[int]bool, len(existing))
+	for _, id := range existing {
+		known[id] = true
+	}
+
+	for position, id := range image_ids {
+		if !known[id] {
+			tx.Rollback()
+			return ErrImagesMismatch
+		}
+		delete(known, id)
+
+		if err := tx.Model(&ds.GroupImage{}).Where("id = ?", id).Update("position", position).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit().Error
+}
+
+func (r *Repository) SetGroupCoverImage(id int) error {
+	tx := r.db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	image := ds.GroupImage{}
+	if err := tx.First(&image, "id = ?", id).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Model(&ds.GroupImage{}).Where("group_refer = ?", image.GroupRefer).Update("is_cover", gorm.Expr("id = ?", image.ID)).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := setGroupCover(tx, image.GroupRefer, image.ImageName); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+// DeleteGroupImage удаляет картинку из галереи и возвращает её, чтобы
+// вызывающий код мог удалить объект из хранилища. Если удаляется обложка,
+// обложкой становится следующая по порядку картинка.
+func (r *Repository) DeleteGroupImage(id int) (*ds.GroupImage, error) {
+	tx := r.db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	image := &ds.GroupImage{}
+	if err := tx.First(image, "id = ?", id).Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Delete(&ds.GroupImage{}, image.ID).Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if image.IsCover {
+		next := ds.GroupImage{}
+		err := tx.Where("group_refer = ?", image.GroupRefer).Order("position").Limit(1).Find(&next).Error
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+
+		if next.ID != 0 {
+			err = tx.Model(&ds.GroupImage{}).Where("id = ?", next.ID).Update("is_cover", true).Error
+			if err != nil {
+				tx.Rollback()
+				return nil, err
+			}
+		}
+
+		if err := setGroupCover(tx, image.GroupRefer, next.ImageName); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
+	return image, tx.Commit().Error
+}
+
+func setGroupCover(tx *gorm.DB, group_id int, image_name string) error {
+	return tx.Model(&ds.Group{}).Where("id = ?", group_id).Update("image_name", image_name).Error
+}
@@ -0,0 +1,257 @@
+package repository
+
+import (
+	"slices"
+	"strconv"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"sports_courses/internal/app/ds"
+)
+
+// GroupSearchVector - документ полнотекстового поиска по группе. Выражение
+// должно совпадать с индексом, который создаёт cmd/migrate, иначе Postgres
+// не сможет им воспользоваться.
+const GroupSearchVector = `(setweight(to_tsvector('russian', coalesce(title, '')), 'A') || ` +
+	`setweight(to_tsvector('russian', coalesce(description, '')), 'C'))`
+
+// GroupTrigramDocument - текст, по которому ищутся опечатки через pg_trgm.
+const GroupTrigramDocument = `(lower(coalesce(title, '')))`
+
+// NameSearchVector и NameTrigramDocument - то же самое для курсов и тренеров:
+// группа находится и по названию своего курса, и по имени своего тренера.
+const (
+	NameSearchVector    = `to_tsvector('russian', coalesce(name, ''))`
+	NameTrigramDocument = `(lower(coalesce(name, '')))`
+)
+
+const groupTrigramThreshold = 0.4
+
+const groupFreeSeatsCondition = `coalesce(nullif(enrolled, ''), '0')::numeric < nullif(capacity, '')::numeric`
+
+// Weekdays - дни недели в том виде, в котором они принимаются фильтром weekday.
+var Weekdays = []string{"пн", "вт", "ср", "чт", "пт", "сб", "вс"}
+
+// расписание хранится свободным текстом, поэтому день ищется и по сокращению, и по полному названию
+var weekdayPatterns = map[string]string{
+	"пн": `\m(пн|понедельник)`,
+	"вт": `\m(вт|вторник)`,
+	"ср": `\m(ср|сред)`,
+	"чт": `\m(чт|четверг)`,
+	"пт": `\m(пт|пятниц)`,
+	"сб": `\m(сб|суббот)`,
+	"вс": `\m(вс|воскресень)`,
+}
+
+const (
+	facetCourse       = "course"
+	facetLocation     = "location"
+	facetWeekday      = "weekday"
+	facetStatus       = "status"
+	facetHasFreeSeats = "has_free_seats"
+)
+
+// applyGroupFilter накладывает фильтр на запрос, пропуская измерение skip.
+func applyGroupFilter(tx *gorm.DB, filter ds.GroupFilter, skip string) *gorm.DB {
+	if filter.TitlePattern != "" {
+		tx = tx.Where("title ilike ?", "%"+filter.TitlePattern+"%")
+	}
+
+	if filter.Query != "" {
+		tx = tx.Where("("+GroupSearchVector+" @@ websearch_to_tsquery('russian', ?) OR word_similarity(lower(?), "+GroupTrigramDocument+") > ? OR "+
+			"groups.course_refer IN (?) OR groups.coach_refer IN (?))",
+			filter.Query, filter.Query, groupTrigramThreshold,
+			matchByName(tx, &ds.Course{}, filter.Query), matchByName(tx, &ds.Coach{}, filter.Query))
+	}
+
+	// группы без семестра показываются в любом семестре
+	if filter.TermID != 0 {
+		tx = tx.Where("(groups.term_refer IS NULL OR groups.term_refer = ?)", filter.TermID)
+	}
+
+	if filter.CoachID != 0 {
+		tx = tx.Where("groups.coach_refer = ?", filter.CoachID)
+	}
+
+	if filter.Course != "" && skip != facetCourse {
+		tx = tx.Where("groups.course_refer IN (?)", tx.Session(&gorm.Session{NewDB: true}).Model(&ds.Course{}).Select("id").Where("lower(name) = lower(trim(?))", filter.Course))
+	}
+
+	if filter.CourseID != 0 && skip != facetCourse {
+		tx = tx.Where("groups.course_refer = ?", filter.CourseID)
+	}
+
+	if filter.Location != "" && skip != facetLocation {
+		tx = tx.Where("groups.location_refer IN (?)", tx.Session(&gorm.Session{NewDB: true}).Model(&ds.Location{}).Select("id").Where("lower("+LocationLabel+") = lower(trim(?))", filter.Location))
+	}
+
+	if filter.LocationID != 0 && skip != facetLocation {
+		tx = tx.Where("groups.location_refer = ?", filter.LocationID)
+	}
+
+	if filter.Weekday != "" && skip != facetWeekday {
+		condition, vars, ok := weekdayCondition(filter.Weekday)
+		if !ok {
+			// неизвестный день недели не должен молча превращаться в "все группы"
+			tx = tx.Where("false")
+		} else {
+			tx = tx.Where(condition, vars...)
+		}
+	}
+
+	if filter.Status != "" && skip != facetStatus {
+		tx = tx.Where("status = ?", filter.Status)
+	}
+
+	if filter.HasFreeSeats != nil && skip != facetHasFreeSeats {
+		if *filter.HasFreeSeats {
+			tx = tx.Where(groupFreeSeatsCondition)
+		} else {
+			tx = tx.Where("not coalesce(" + groupFreeSeatsCondition + ", false)")
+		}
+	}
+
+	return tx
+}
+
+// weekdayCondition - условие "группа занимается в день weekday". Если у
+// группы заведены занятия, день берётся из них, иначе ищется в тексте расписания.
+func weekdayCondition(weekday string) (string, []interface{}, bool) {
+	pattern, ok := weekdayPatterns[weekday]
+	if !ok {
+		return "", nil, false
+	}
+
+	return `(EXISTS (SELECT 1 FROM group_sessions WHERE group_sessions.group_refer = groups.id AND group_sessions.weekday = ?) OR ` +
+			`(NOT EXISTS (SELECT 1 FROM group_sessions WHERE group_sessions.group_refer = groups.id) AND schedule ~* ?))`,
+		[]interface{}{slices.Index(Weekdays, weekday) + 1, pattern}, true
+}
+
+// matchByName возвращает подзапрос с id курсов или тренеров, чьё название
+// подходит под поисковый запрос.
+func matchByName(tx *gorm.DB, model interface{}, query string) *gorm.DB {
+	return tx.Session(&gorm.Session{NewDB: true}).Model(model).Select("id").
+		Where(NameSearchVector+" @@ websearch_to_tsquery('russian', ?) OR word_similarity(lower(?), "+NameTrigramDocument+") > ?",
+			query, query, groupTrigramThreshold)
+}
+
+// orderGroupsByRelevance сортирует результаты полнотекстового поиска по
+// релевантности: сначала ранг ts_rank, затем похожесть с учётом опечаток.
+func orderGroupsByRelevance(tx *gorm.DB, query string) *gorm.DB {
+	if query == "" {
+		return tx
+	}
+
+	return tx.Clauses(clause.OrderBy{Expression: clause.Expr{
+		SQL:                "ts_rank(" + GroupSearchVector + ", websearch_to_tsquery('russian', ?)) + word_similarity(lower(?), " + GroupTrigramDocument + ") DESC",
+		Vars:               []interface{}{query, query},
+		WithoutParentheses: true,
+	}})
+}
+
+func (r *Repository) GetGroupFacets(filter ds.GroupFilter) (ds.GroupFacets, error) {
+	facets := ds.GroupFacets{}
+	var err error
+
+	facets.Courses, err = r.countGroupsBy("(SELECT name FROM courses WHERE courses.id = groups.course_refer)", filter, facetCourse)
+	if err != nil {
+		return ds.GroupFacets{}, err
+	}
+
+	facets.Locations, err = r.countGroupsBy("(SELECT "+LocationLabel+" FROM locations WHERE locations.id = groups.location_refer)", filter, facetLocation)
+	if err != nil {
+		return ds.GroupFacets{}, err
+	}
+
+	facets.Statuses, err = r.countGroupsBy("status", filter, facetStatus)
+	if err != nil {
+		return ds.GroupFacets{}, err
+	}
+
+	facets.Weekdays, err = r.countGroupsByWeekday(filter)
+	if err != nil {
+		return ds.GroupFacets{}, err
+	}
+
+	facets.HasFreeSeats, err = r.countGroupsByFreeSeats(filter)
+	if err != nil {
+		return ds.GroupFacets{}, err
+	}
+
+	return facets, nil
+}
+
+type facetCount struct {
+	Value *string
+	Count int64
+}
+
+func (r *Repository) countGroupsBy(column string, filter ds.GroupFilter, skip string) (map[string]int64, error) {
+	var rows []facetCount
+
+	tx := applyGroupFilter(r.db.Model(&ds.Group{}), filter, skip)
+	err := tx.Select(column + " AS value, count(*) AS count").Group("value").Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		value := ""
+		if row.Value != nil {
+			value = *row.Value
+		}
+		result[value] += row.Count
+	}
+
+	return result, nil
+}
+
+func (r *Repository) countGroupsByWeekday(filter ds.GroupFilter) (map[string]int64, error) {
+	selects := ""
+	vars := make([]interface{}, 0, 2*len(Weekdays))
+	for i, weekday := range Weekdays {
+		if i > 0 {
+			selects += ", "
+		}
+		condition, conditionVars, _ := weekdayCondition(weekday)
+		selects += "count(*) FILTER (WHERE " + condition + ") AS d" + strconv.Itoa(i)
+		vars = append(vars, conditionVars...)
+	}
+
+	counts := make([]int64, len(Weekdays))
+	dest := make([]interface{}, len(Weekdays))
+	for i := range counts {
+		dest[i] = &counts[i]
+	}
+
+	tx := applyGroupFilter(r.db.Model(&ds.Group{}), filter, facetWeekday)
+	err := tx.Select(selects, vars...).Row().Scan(dest...)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]int64, len(Weekdays))
+	for i, weekday := range Weekdays {
+		result[weekday] = counts[i]
+	}
+
+	return result, nil
+}
+
+func (r *Repository) countGroupsByFreeSeats(filter ds.GroupFilter) (map[string]int64, error) {
+	var withSeats, withoutSeats int64
+
+	tx := applyGroupFilter(r.db.Model(&ds.Group{}), filter, facetHasFreeSeats)
+	err := tx.Select("count(*) FILTER (WHERE "+groupFreeSeatsCondition+"), "+
+		"count(*) FILTER (WHERE NOT coalesce("+groupFreeSeatsCondition+", false))").Row().Scan(&withSeats, &withoutSeats)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]int64{
+		"true":  withSeats,
+		"false": withoutSeats,
+	}, nil
+}
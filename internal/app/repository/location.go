@@ -0,0 +1,260 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"sports_courses/internal/app/ds"
+)
+
+var ErrLocationInUse = errors.New("место используется группами")
+
+var LocationSortColumns = []string{"id", "building", "room", "capacity"}
+
+// LocationLabel - название места в том виде, в каком оно показывается в
+// фильтрах каталога: "корпус, помещение".
+const LocationLabel = `(building || coalesce(', ' || nullif(room, ''), ''))`
+
+// InactiveGroupStatuses - статусы групп, которые не занимают места в расписании.
+var InactiveGroupStatuses = []string{"Недоступен", "Недействителен"}
+
+// sessionLocation - место, где фактически проходит занятие.
+const sessionLocation = `coalesce(group_sessions.location_refer, groups.location_refer)`
+
+// LocationConflictError возвращается при сохранении группы, занятия которой
+// пересекаются по времени с занятиями других групп в том же месте.
+type LocationConflictError struct {
+	Conflicts []ds.LocationConflict
+}
+
+func (e *LocationConflictError) Error() string {
+	lines := make([]string, 0, len(e.Conflicts))
+	for _, conflict := range e.Conflicts {
+		lines = append(lines, fmt.Sprintf("день %d %s-%s занят группой %q (%s-%s)",
+			conflict.Session.Weekday, conflict.Session.StartTime, conflict.Session.EndTime,
+			conflict.Occupied.GroupTitle, conflict.Occupied.StartTime, conflict.Occupied.EndTime))
+	}
+
+	return "место уже занято: " + strings.Join(lines, "; ")
+}
+
+func (r *Repository) CreateLocation(location *ds.Location) error {
+	return r.db.Create(location).Error
+}
+
+func (r *Repository) EditLocation(location *ds.Location) error {
+	return r.db.Model(&ds.Location{}).Where("id = ?", location.ID).Updates(location).Error
+}
+
+func (r *Repository) GetLocationByID(id int) (*ds.Location, error) {
+	location := &ds.Location{}
+
+	err := r.db.First(location, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return location, nil
+}
+
+func (r *Repository) GetLocations(page ds.PageRequest) ([]ds.Location, ds.PageInfo, error) {
+	locations := []ds.Location{}
+
+	if len(page.Sort) == 0 {
+		page.Sort = []ds.SortField{{Column: "building"}, {Column: "room"}}
+	}
+
+	info, err := paginate(r.db.Model(&ds.Location{}), page, &locations)
+	if err != nil {
+		return nil, ds.PageInfo{}, err
+	}
+
+	return locations, info, nil
+}
+
+// DeleteLocation удаляет место, если в нём не проходят занятия ни одной группы.
+func (r *Repository) DeleteLocation(id int) error {
+	var used int64
+
+	err := r.db.Model(&ds.Group{}).Where("location_refer = ?", id).Count(&used).Error
+	if err != nil {
+		return err
+	}
+
+	if used == 0 {
+		err = r.db.Model(&ds.GroupSession{}).Where("location_refer = ?", id).Count(&used).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	if used > 0 {
+		return ErrLocationInUse
+	}
+
+	return r.db.Delete(&ds.Location{}, id).Error
+}
+
+// GetLocationOccupancy возвращает занятия всех действующих групп в месте по
+// дням недели, с понедельника по воскресенье. Если term_id не 0, учитываются
+// только группы этого семестра и группы без семестра.
+func (r *Repository) GetLocationOccupancy(id int, term_id uint) ([]ds.LocationOccupancyDay, error) {
+	slots := []ds.LocationSlot{}
+
+	tx := locationSlots(r.db).Where(sessionLocation+" = ?", id)
+	if term_id != 0 {
+		tx = sameTerm(tx, &term_id)
+	}
+
+	err := tx.Order("group_sessions.weekday, group_sessions.start_time").Scan(&slots).Error
+	if err != nil {
+		return nil, err
+	}
+
+	days := make([]ds.LocationOccupancyDay, 7)
+	for i := range days {
+		days[i] = ds.LocationOccupancyDay{Weekday: i + 1, Slots: []ds.LocationSlot{}}
+	}
+
+	for _, slot := range slots {
+		if slot.Weekday >= 1 && slot.Weekday <= 7 {
+			days[slot.Weekday-1].Slots = append(days[slot.Weekday-1].Slots, slot)
+		}
+	}
+
+	return days, nil
+}
+
+func locationSlots(tx *gorm.DB) *gorm.DB {
+	return tx.Model(&ds.GroupSession{}).
+		Select("group_sessions.id AS session_id, groups.id AS group_id, groups.title AS group_title, "+
+			sessionLocation+" AS location_id, group_sessions.weekday, group_sessions.start_time, group_sessions.end_time").
+		Joins("JOIN groups ON groups.id = group_sessions.group_refer").
+		Where("groups.status NOT IN ?", InactiveGroupStatuses)
+}
+
+// sameTerm оставляет группы, которые могут заниматься одновременно с группой
+// семестра term: того же семестра или без семестра.
+func sameTerm(tx *gorm.DB, term *uint) *gorm.DB {
+	if term == nil {
+		return tx
+	}
+
+	return tx.Where("(groups.term_refer IS NULL OR groups.term_refer = ?)", *term)
+}
+
+// findLocationConflicts ищет занятия других групп, которые пересекаются с
+// sessions по месту, дню недели и времени. Занятия без места наследуют
+// groupLocation. Сами sessions тоже проверяются друг с другом.
+func findLocationConflicts(tx *gorm.DB, group_id uint, term *uint, groupLocation *uint, sessions []ds.GroupSession) ([]ds.LocationConflict, error) {
+	conflicts := []ds.LocationConflict{}
+
+	effective := func(session ds.GroupSession) *uint {
+		if session.LocationRefer != nil {
+			return session.LocationRefer
+		}
+		return groupLocation
+	}
+
+	for i, session := range sessions {
+		location := effective(session)
+		if location == nil {
+			continue
+		}
+
+		for _, other := range sessions[:i] {
+			otherLocation := effective(other)
+			if otherLocation != nil && *otherLocation == *location && other.Weekday == session.Weekday &&
+				other.StartTime < session.EndTime && session.StartTime < other.EndTime {
+				conflicts = append(conflicts, ds.LocationConflict{
+					Session: session,
+					Occupied: ds.LocationSlot{
+						GroupID:    group_id,
+						LocationID: *location,
+						Weekday:    other.Weekday,
+						StartTime:  other.StartTime,
+						EndTime:    other.EndTime,
+					},
+				})
+			}
+		}
+
+		slots := []ds.LocationSlot{}
+		err := sameTerm(locationSlots(tx), term).
+			Where("groups.id <> ?", group_id).
+			Where(sessionLocation+" = ?", *location).
+			Where("group_sessions.weekday = ?", session.Weekday).
+			Where("group_sessions.start_time < ? AND ? < group_sessions.end_time", session.EndTime, session.StartTime).
+			Scan(&slots).Error
+		if err != nil {
+			return nil, err
+		}
+
+		for _, slot := range slots {
+			conflicts = append(conflicts, ds.LocationConflict{Session: session, Occupied: slot})
+		}
+	}
+
+	return conflicts, nil
+}
+
+// checkGroupSchedule проверяет, что занятия группы group_id не пересекаются
+// с занятиями других групп того же семестра. Недействующие группы места не
+// занимают. Вызывается внутри транзакции, которая потом сохраняет группу.
+func checkGroupSchedule(tx *gorm.DB, group_id uint, status string, term *uint, location *uint, sessions []ds.GroupSession) error {
+	if err := lockSchedule(tx); err != nil {
+		return err
+	}
+
+	if isInactiveGroupStatus(status) {
+		return nil
+	}
+
+	conflicts, err := findLocationConflicts(tx, group_id, term, location, sessions)
+	if err != nil {
+		return err
+	}
+
+	if len(conflicts) > 0 {
+		return &LocationConflictError{Conflicts: conflicts}
+	}
+
+	return nil
+}
+
+// detachSessions готовит занятия к вставке в группу group_id: место
+// задаётся только через LocationRefer, а id выдаёт база.
+func detachSessions(sessions []ds.GroupSession, group_id uint) []ds.GroupSession {
+	result := make([]ds.GroupSession, 0, len(sessions))
+	for _, session := range sessions {
+		session.ID = 0
+		session.GroupRefer = int(group_id)
+		session.Location = nil
+		result = append(result, session)
+	}
+
+	return result
+}
+
+func orderSessions(tx *gorm.DB) *gorm.DB {
+	return tx.Order("weekday, start_time")
+}
+
+// lockSchedule сериализует изменения расписания, чтобы две транзакции не
+// заняли одно и то же место, не видя друг друга.
+func lockSchedule(tx *gorm.DB) error {
+	return tx.Exec(`SELECT pg_advisory_xact_lock(hashtext('group_sessions'))`).Error
+}
+
+func isInactiveGroupStatus(status string) bool {
+	for _, inactive := range InactiveGroupStatuses {
+		if status == inactive {
+			return true
+		}
+	}
+
+	return false
+}
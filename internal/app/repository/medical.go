@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+
+	"sports_courses/internal/app/ds"
+)
+
+var ErrCertificateReviewed = errors.New("справка уже проверена")
+
+var CertificateSortColumns = []string{"id", "date_uploaded", "expires_at"}
+
+func (r *Repository) CreateMedicalCertificate(certificate *ds.MedicalCertificate) error {
+	return r.db.Omit("User").Create(certificate).Error
+}
+
+func (r *Repository) GetMedicalCertificateByID(id int) (*ds.MedicalCertificate, error) {
+	certificate := &ds.MedicalCertificate{}
+
+	err := r.db.First(certificate, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return certificate, nil
+}
+
+func (r *Repository) GetUserMedicalCertificates(userUUID uuid.UUID) ([]ds.MedicalCertificate, error) {
+	certificates := []ds.MedicalCertificate{}
+
+	err := r.db.Where("user_refer = ?", userUUID).Order("date_uploaded DESC").Find(&certificates).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return certificates, nil
+}
+
+// GetMedicalCertificates возвращает справки со статусом status вместе со
+// студентами и их профилями. По умолчанию первыми идут самые старые.
+func (r *Repository) GetMedicalCertificates(status string, page ds.PageRequest) ([]ds.MedicalCertificate, ds.PageInfo, error) {
+	certificates := []ds.MedicalCertificate{}
+
+	tx := r.db.Model(&ds.MedicalCertificate{}).Joins("User")
+	if status != "" {
+		tx = tx.Where("medical_certificates.status = ?", status)
+	}
+
+	if len(page.Sort) == 0 {
+		page.Sort = []ds.SortField{{Column: "date_uploaded"}}
+	}
+
+	info, err := paginate(tx, page, &certificates)
+	if err != nil {
+		return nil, ds.PageInfo{}, err
+	}
+
+	users := make([]uuid.UUID, 0, len(certificates))
+	for _, certificate := range certificates {
+		users = append(users, certificate.UserRefer)
+	}
+
+	profiles, err := r.loadProfiles(users)
+	if err != nil {
+		return nil, ds.PageInfo{}, err
+	}
+
+	for i := range certificates {
+		certificates[i].User.Profile = profiles[certificates[i].UserRefer]
+	}
+
+	return certificates, info, nil
+}
+
+// ReviewMedicalCertificate одобряет или отклоняет справку, которая ещё на проверке.
+func (r *Repository) ReviewMedicalCertificate(id int, moderator uuid.UUID, approve bool, expiresAt time.Time, comment string) error {
+	status := ds.CertificateRejected
+	updates := map[string]interface{}{
+		"moderator_refer": moderator,
+		"date_processed":  time.Now(),
+		"comment":         comment,
+	}
+
+	if approve {
+		status = ds.CertificateApproved
+		updates["expires_at"] = expiresAt
+	}
+	updates["status"] = status
+
+	result := r.db.Model(&ds.MedicalCertificate{}).Where("id = ? AND status = ?", id, ds.CertificatePending).Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		if _, err := r.GetMedicalCertificateByID(id); err != nil {
+			return err
+		}
+		return ErrCertificateReviewed
+	}
+
+	return nil
+}
+
+// HasValidMedicalCertificate сообщает, есть ли у студента одобренная справка,
+// действующая в момент at.
+func (r *Repository) HasValidMedicalCertificate(userUUID uuid.UUID, at time.Time) (bool, error) {
+	var count int64
+
+	err := r.db.Model(&ds.MedicalCertificate{}).
+		Where("user_refer = ? AND status = ? AND expires_at > ?", userUUID, ds.CertificateApproved, at).
+		Count(&count).Error
+
+	return count > 0, err
+}
+
+// GetClearanceGroups возвращает названия групп записи, для которых нужна медицинская справка.
+func (r *Repository) GetClearanceGroups(enrollment_id int) ([]string, error) {
+	titles := []string{}
+
+	err := r.db.Model(&ds.Group{}).
+		Where("id IN (?)", r.db.Model(&ds.EnrollmentToGroup{}).Select("group_refer").Where("enrollment_refer = ?", enrollment_id)).
+		Where("requires_clearance").
+		Order("title").
+		Pluck("title", &titles).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return titles, nil
+}
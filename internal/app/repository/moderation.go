@@ -0,0 +1,34 @@
+package repository
+
+import (
+	"sports_courses/internal/app/ds"
+)
+
+// moderationQueueLimit ограничивает размер очереди, которую получает модератор при подключении.
+const moderationQueueLimit = 500
+
+// GetModerationQueue возвращает сформированные записи, ждущие решения
+// модератора, вместе со студентами, их профилями и группами. Первыми идут
+// самые старые.
+func (r *Repository) GetModerationQueue() ([]ds.Enrollment, error) {
+	enrollments := []ds.Enrollment{}
+
+	err := r.db.Joins("User").
+		Where("enrollments.status = ?", "Сформирован").
+		Order("enrollments.date_created, enrollments.id").
+		Limit(moderationQueueLimit).
+		Find(&enrollments).Error
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.loadEnrollmentGroups(enrollments); err != nil {
+		return nil, err
+	}
+
+	if err := r.loadEnrollmentProfiles(enrollments); err != nil {
+		return nil, err
+	}
+
+	return enrollments, nil
+}
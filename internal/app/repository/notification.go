@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"sports_courses/internal/app/ds"
+)
+
+var NotificationSortColumns = []string{"id", "created_at"}
+
+// GetNotifications возвращает уведомления пользователя, по умолчанию сначала новые.
+func (r *Repository) GetNotifications(userUUID uuid.UUID, unread bool, page ds.PageRequest) ([]ds.Notification, ds.PageInfo, error) {
+	notifications := []ds.Notification{}
+
+	tx := r.db.Model(&ds.Notification{}).Where("user_refer = ?", userUUID)
+	if unread {
+		tx = tx.Where("read_at IS NULL")
+	}
+
+	if len(page.Sort) == 0 {
+		page.Sort = []ds.SortField{{Column: "id", Desc: true}}
+	}
+
+	info, err := paginate(tx, page, &notifications)
+	if err != nil {
+		return nil, ds.PageInfo{}, err
+	}
+
+	return notifications, info, nil
+}
+
+func (r *Repository) CountUnreadNotifications(userUUID uuid.UUID) (int64, error) {
+	var count int64
+
+	err := r.db.Model(&ds.Notification{}).Where("user_refer = ? AND read_at IS NULL", userUUID).Count(&count).Error
+
+	return count, err
+}
+
+// MarkNotificationRead отмечает прочитанным уведомление пользователя. Чужое
+// уведомление считается ненайденным.
+func (r *Repository) MarkNotificationRead(userUUID uuid.UUID, id int) error {
+	result := r.db.Model(&ds.Notification{}).
+		Where("id = ? AND user_refer = ?", id, userUUID).
+		Update("read_at", gorm.Expr("COALESCE(read_at, ?)", time.Now()))
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// MarkAllNotificationsRead отмечает прочитанными все уведомления пользователя
+// и возвращает, сколько их было.
+func (r *Repository) MarkAllNotificationsRead(userUUID uuid.UUID) (int64, error) {
+	result := r.db.Model(&ds.Notification{}).
+		Where("user_refer = ? AND read_at IS NULL", userUUID).
+		Update("read_at", time.Now())
+
+	return result.RowsAffected, result.Error
+}
+
+// GetNotificationPreferences возвращает настройки по всем типам уведомлений,
+// включая те, которые пользователь не менял.
+func (r *Repository) GetNotificationPreferences(userUUID uuid.UUID) ([]ds.NotificationPreference, error) {
+	saved := []ds.NotificationPreference{}
+	if err := r.db.Where("user_refer = ?", userUUID).Find(&saved).Error; err != nil {
+		return nil, err
+	}
+
+	enabled := make(map[string]bool, len(saved))
+	for _, preference := range saved {
+		enabled[preference.Type] = preference.Enabled
+	}
+
+	preferences := make([]ds.NotificationPreference, 0, len(ds.EventTypes))
+	for _, eventType := range ds.EventTypes {
+		value, ok := enabled[eventType]
+		preferences = append(preferences, ds.NotificationPreference{
+			UserRefer: userUUID,
+			Type:      eventType,
+			Enabled:   !ok || value,
+		})
+	}
+
+	return preferences, nil
+}
+
+func (r *Repository) SetNotificationPreferences(userUUID uuid.UUID, preferences []ds.NotificationPreferenceRequestBody) error {
+	if len(preferences) == 0 {
+		return nil
+	}
+
+	rows := make([]ds.NotificationPreference, 0, len(preferences))
+	for _, preference := range preferences {
+		rows = append(rows, ds.NotificationPreference{
+			UserRefer: userUUID,
+			Type:      preference.Type,
+			Enabled:   preference.Enabled,
+		})
+	}
+
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_refer"}, {Name: "type"}},
+		DoUpdates: clause.AssignmentColumns([]string{"enabled"}),
+	}).Create(&rows).Error
+}
@@ -0,0 +1,203 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"sports_courses/internal/app/ds"
+)
+
+var (
+	ErrInvalidCursor     = errors.New("передан некорректный курсор")
+	ErrCursorUnsupported = errors.New("курсор не поддерживается для сортировки по релевантности")
+)
+
+// GroupSortColumns и EnrollmentSortColumns - колонки, по которым разрешено
+// сортировать списки. Всё, что не перечислено, отклоняется ещё в обработчике.
+var (
+	GroupSortColumns      = []string{"id", "title", "course_refer", "location_refer", "status"}
+	EnrollmentSortColumns = []string{"id", "status", "date_created", "date_processed", "date_finished"}
+)
+
+// nullableSortColumns - колонки сортировки, в которых бывает NULL. С NULL
+// сравнение в курсоре ложно, и такие строки выпадали бы со страниц, поэтому
+// эти колонки сортируются и сравниваются как COALESCE(колонка, 0).
+var nullableSortColumns = map[string]bool{
+	"course_refer":   true,
+	"location_refer": true,
+}
+
+// paginate считает общее количество строк запроса tx и загружает в dest
+// (указатель на слайс моделей) одну страницу. Если в page.Sort нет колонок,
+// используется сортировка, уже наложенная на tx, и курсор недоступен.
+func paginate(tx *gorm.DB, page ds.PageRequest, dest interface{}) (ds.PageInfo, error) {
+	info := ds.PageInfo{}
+
+	tx = tx.Session(&gorm.Session{})
+	if err := tx.Count(&info.Total).Error; err != nil {
+		return ds.PageInfo{}, err
+	}
+
+	sort := page.Sort
+	if len(sort) > 0 {
+		sort = withTieBreaker(sort)
+	} else if page.Cursor != "" {
+		return ds.PageInfo{}, ErrCursorUnsupported
+	}
+
+	query := tx
+	if page.Cursor != "" {
+		values, err := decodeCursor(page.Cursor, len(sort))
+		if err != nil {
+			return ds.PageInfo{}, err
+		}
+		query = query.Where(keysetCondition(sort, values))
+	} else if page.Offset > 0 {
+		query = query.Offset(page.Offset)
+	}
+
+	if len(sort) > 0 {
+		query = query.Clauses(orderBySort(sort))
+	}
+
+	if err := query.Limit(page.Limit + 1).Find(dest).Error; err != nil {
+		return ds.PageInfo{}, err
+	}
+
+	rows := reflect.ValueOf(dest).Elem()
+	if rows.Len() <= page.Limit {
+		return info, nil
+	}
+
+	rows.Set(rows.Slice(0, page.Limit))
+	info.HasMore = true
+
+	if len(sort) > 0 {
+		cursor, err := encodeCursor(tx, sort, rows.Index(page.Limit-1))
+		if err != nil {
+			return ds.PageInfo{}, err
+		}
+		info.NextCursor = cursor
+	}
+
+	return info, nil
+}
+
+// withTieBreaker добавляет сортировку по id, чтобы порядок строк был
+// однозначным и курсор всегда указывал ровно на одну строку.
+func withTieBreaker(sort []ds.SortField) []ds.SortField {
+	for _, field := range sort {
+		if field.Column == "id" {
+			return sort
+		}
+	}
+
+	return append(append([]ds.SortField{}, sort...), ds.SortField{Column: "id"})
+}
+
+// orderBySort сортирует по колонкам sort так же, как их сравнивает курсор.
+func orderBySort(sort []ds.SortField) clause.OrderBy {
+	terms := make([]string, 0, len(sort))
+	vars := make([]interface{}, 0, len(sort))
+
+	for _, field := range sort {
+		term := "?"
+		if field.Desc {
+			term += " DESC"
+		}
+		terms = append(terms, term)
+		vars = append(vars, sortKey(field.Column))
+	}
+
+	return clause.OrderBy{Expression: clause.Expr{SQL: strings.Join(terms, ", "), Vars: vars, WithoutParentheses: true}}
+}
+
+// keysetCondition строит условие "строка идёт после курсора" для
+// произвольного набора колонок с разными направлениями сортировки:
+// (a > ?) OR (a = ? AND b < ?) OR ...
+func keysetCondition(sort []ds.SortField, values []interface{}) clause.Expression {
+	var branches []clause.Expression
+
+	for i, field := range sort {
+		var terms []clause.Expression
+
+		for j := 0; j < i; j++ {
+			terms = append(terms, clause.Expr{SQL: "? = ?", Vars: []interface{}{sortKey(sort[j].Column), values[j]}})
+		}
+
+		if field.Desc {
+			terms = append(terms, clause.Expr{SQL: "? < ?", Vars: []interface{}{sortKey(field.Column), values[i]}})
+		} else {
+			terms = append(terms, clause.Expr{SQL: "? > ?", Vars: []interface{}{sortKey(field.Column), values[i]}})
+		}
+
+		branches = append(branches, clause.And(terms...))
+	}
+
+	return clause.Or(branches...)
+}
+
+func currentColumn(name string) clause.Column {
+	return clause.Column{Table: clause.CurrentTable, Name: name}
+}
+
+// sortKey - выражение, по которому сортируется и сравнивается колонка.
+func sortKey(name string) interface{} {
+	if nullableSortColumns[name] {
+		return clause.Expr{SQL: "COALESCE(?, 0)", Vars: []interface{}{currentColumn(name)}}
+	}
+
+	return currentColumn(name)
+}
+
+func encodeCursor(tx *gorm.DB, sort []ds.SortField, row reflect.Value) (string, error) {
+	stmt := &gorm.Statement{DB: tx}
+	if err := stmt.Parse(row.Addr().Interface()); err != nil {
+		return "", err
+	}
+
+	values := make([]interface{}, 0, len(sort))
+	for _, field := range sort {
+		schemaField := stmt.Schema.LookUpField(field.Column)
+		if schemaField == nil {
+			return "", ErrInvalidCursor
+		}
+
+		value, zero := schemaField.ValueOf(context.Background(), row)
+		if zero && nullableSortColumns[field.Column] {
+			value = 0
+		}
+		values = append(values, value)
+	}
+
+	data, err := json.Marshal(values)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func decodeCursor(cursor string, size int) ([]interface{}, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var values []interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&values); err != nil || len(values) != size {
+		return nil, ErrInvalidCursor
+	}
+
+	return values, nil
+}
@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"strings"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"sports_courses/internal/app/ds"
+)
+
+// SaveStudentProfile создаёт профиль студента или заменяет существующий.
+func (r *Repository) SaveStudentProfile(profile *ds.StudentProfile) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_refer"}},
+		UpdateAll: true,
+	}).Create(profile).Error
+}
+
+// loadProfiles загружает профили переданных пользователей одним запросом.
+func (r *Repository) loadProfiles(users []uuid.UUID) (map[uuid.UUID]*ds.StudentProfile, error) {
+	result := make(map[uuid.UUID]*ds.StudentProfile, len(users))
+	if len(users) == 0 {
+		return result, nil
+	}
+
+	profiles := []ds.StudentProfile{}
+	if err := r.db.Where("user_refer IN ?", users).Find(&profiles).Error; err != nil {
+		return nil, err
+	}
+
+	for i := range profiles {
+		result[profiles[i].UserRefer] = &profiles[i]
+	}
+
+	return result, nil
+}
+
+// filterProfiles возвращает подзапрос с пользователями, чьи профили подходят
+// под фильтр записей, или nil, если по профилю фильтровать не нужно.
+func filterProfiles(db *gorm.DB, filter ds.EnrollmentFilter) *gorm.DB {
+	if filter.StudyGroup == "" && filter.Faculty == "" && filter.Year == 0 && filter.MedicalGroup == "" {
+		return nil
+	}
+
+	tx := db.Model(&ds.StudentProfile{}).Select("user_refer")
+
+	if filter.StudyGroup != "" {
+		// ИУ5 находит все группы кафедры, ИУ5-31Б - одну группу
+		tx = tx.Where("study_group ILIKE ?", escapeLike(filter.StudyGroup)+"%")
+	}
+
+	if filter.Faculty != "" {
+		tx = tx.Where("lower(faculty) = lower(?)", filter.Faculty)
+	}
+
+	if filter.Year != 0 {
+		tx = tx.Where("year = ?", filter.Year)
+	}
+
+	if filter.MedicalGroup != "" {
+		tx = tx.Where("medical_group = ?", filter.MedicalGroup)
+	}
+
+	return tx
+}
+
+func escapeLike(value string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return replacer.Replace(value)
+}
@@ -1,11 +1,14 @@
 package repository
 
 import (
+	"database/sql"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
 	"sports_courses/internal/app/ds"
 	"sports_courses/internal/app/role"
@@ -114,69 +117,84 @@ func (r *Repository) GetUserRole(name string) (role.Role, error) {
 	return user.Role, nil
 }
 
-func (r *Repository) GetGroups(title_pattern string, course string, status string) ([]ds.Group, error) {
+// GetGroups возвращает страницу каталога. Если сортировка не задана, группы
+// найденные полнотекстовым поиском упорядочиваются по релевантности.
+func (r *Repository) GetGroups(filter ds.GroupFilter, page ds.PageRequest) ([]ds.Group, ds.PageInfo, error) {
 	groups := []ds.Group{}
 
-	var tx *gorm.DB = r.db
+	tx := r.db.Model(&ds.Group{}).Joins("Coach").Preload("Course").Preload("Location").Preload("Sessions", orderSessions)
+	tx = applyGroupFilter(tx, filter, "")
 
-	if title_pattern != "" {
-		tx = tx.Where("title like ?", "%"+title_pattern+"%")
-
-	}
-
-	if course != "" {
-		tx = tx.Where("course = ?", course)
-	}
-
-	if status != "" {
-		tx = tx.Where("status = ?", status)
+	if len(page.Sort) == 0 {
+		if filter.Query != "" {
+			tx = orderGroupsByRelevance(tx, filter.Query)
+		} else {
+			page.Sort = []ds.SortField{{Column: "id"}}
+		}
 	}
 
-	err := tx.Find(&groups).Error
+	info, err := paginate(tx, page, &groups)
 
 	if err != nil {
-		return nil, err
+		return nil, ds.PageInfo{}, err
 	}
 
-	return groups, nil
+	return groups, info, nil
 }
 
-func (r *Repository) GetEnrollments(status string, startDate string, endDate string, roleNumber role.Role, userUUID uuid.UUID) ([]ds.Enrollment, error) {
+func (r *Repository) GetEnrollments(filter ds.EnrollmentFilter, roleNumber role.Role, userUUID uuid.UUID, page ds.PageRequest, expand ds.EnrollmentExpand) ([]ds.Enrollment, ds.PageInfo, error) {
 	enrollments := []ds.Enrollment{}
 
-	var tx *gorm.DB = r.db
-	if status != "" {
-		tx = tx.Where("status = ?", status)
+	var tx *gorm.DB = r.db.Model(&ds.Enrollment{})
+	if filter.Status != "" {
+		tx = tx.Where("enrollments.status = ?", filter.Status)
+	}
+
+	if filter.StartDate != "" {
+		tx = tx.Where("enrollments.date_created >= ?", filter.StartDate)
 	}
 
-	if startDate != "" {
-		tx = tx.Where("date_created >= ?", startDate)
+	if filter.EndDate != "" {
+		tx = tx.Where("enrollments.date_created <= ?", filter.EndDate)
 	}
 
-	if endDate != "" {
-		tx = tx.Where("date_created <= ?", endDate)
+	if filter.TermID != 0 {
+		tx = tx.Where("(enrollments.term_refer IS NULL OR enrollments.term_refer = ?)", filter.TermID)
+	}
+
+	if profiles := filterProfiles(r.db, filter); profiles != nil {
+		tx = tx.Where("enrollments.user_refer IN (?)", profiles)
 	}
 
 	if roleNumber == role.User {
-		tx = tx.Where("user_refer = ?", userUUID)
+		tx = tx.Where("enrollments.user_refer = ?", userUUID)
+	}
+
+	if len(page.Sort) == 0 {
+		page.Sort = []ds.SortField{{Column: "id"}}
 	}
 
-	err := tx.Find(&enrollments).Error
+	info, err := paginate(preloadEnrollments(tx, expand), page, &enrollments)
 
 	if err != nil {
-		return nil, err
+		return nil, ds.PageInfo{}, err
 	}
 
-	for i := range enrollments {
-		if enrollments[i].ModeratorRefer != nil {
-			moderator, _ := r.GetUserByID(*enrollments[i].ModeratorRefer)
-			enrollments[i].Moderator = *moderator
+	if expand.Groups {
+		err = r.loadEnrollmentGroups(enrollments)
+		if err != nil {
+			return nil, ds.PageInfo{}, err
 		}
-		user, _ := r.GetUserByID(*enrollments[i].UserRefer)
-		enrollments[i].User = *user
 	}
 
-	return enrollments, nil
+	if expand.User {
+		err = r.loadEnrollmentProfiles(enrollments)
+		if err != nil {
+			return nil, ds.PageInfo{}, err
+		}
+	}
+
+	return enrollments, info, nil
 }
 
 func (r *Repository) GetDraftEnrollment(user uuid.UUID) (ds.Enrollment, error) {
@@ -187,8 +205,29 @@ func (r *Repository) GetDraftEnrollment(user uuid.UUID) (ds.Enrollment, error) {
 	return enrollment, err
 }
 
+// CreateGroup создаёт группу вместе с её занятиями. Если место уже занято
+// другой группой в то же время, возвращается *LocationConflictError.
 func (r *Repository) CreateGroup(group ds.Group) error {
-	return r.db.Create(&group).Error
+	tx := r.db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := checkGroupSchedule(tx, 0, group.Status, group.TermRefer, group.LocationRefer, group.Sessions); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	group.Sessions = detachSessions(group.Sessions, 0)
+
+	if err := tx.Omit("Coach", "Course", "Location", "Term").Create(&group).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
 }
 
 func (r *Repository) CreateUser(user ds.User) error {
@@ -237,7 +276,21 @@ func (r *Repository) LogicalDeleteEnrollment(enrollment_id int) error {
 	return tx.Commit().Error
 }
 
-func (r *Repository) ModeratorConfirmEnrollment(uuid uuid.UUID, enrollment_id int, confirm bool) error {
+// ModeratorConfirmEnrollment одобряет или отклоняет запись, если она всё ещё
+// сформирована, и возвращает её новый статус. Запись блокируется до конца
+// транзакции, поэтому два модератора не обработают её одновременно. При
+// отклонении rejection обязательна, при одобрении игнорируется.
+func (r *Repository) ModeratorConfirmEnrollment(moderator uuid.UUID, enrollment_id uint, confirm bool, rejection ds.RejectionRequestBody) (string, error) {
+	new_status, reason := "Завершён", ""
+	if !confirm {
+		if err := CheckRejection(rejection); err != nil {
+			return "", err
+		}
+		new_status, reason = "Отклонён", rejectionReason(rejection)
+	} else {
+		rejection = ds.RejectionRequestBody{}
+	}
+
 	tx := r.db.Begin()
 	defer func() {
 		if r := recover(); r != nil {
@@ -245,17 +298,30 @@ func (r *Repository) ModeratorConfirmEnrollment(uuid uuid.UUID, enrollment_id in
 		}
 	}()
 
-	new_status := "Отклонён"
-	if confirm {
-		new_status = "Завершён"
+	enrollment := ds.Enrollment{}
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id", "status").First(&enrollment, "id = ?", enrollment_id).Error
+	if err != nil {
+		tx.Rollback()
+		return "", err
 	}
 
-	if err := tx.Exec(`UPDATE public.enrollments SET status = ?, moderator_refer = ?, date_processed = ? WHERE id = ?`, new_status, uuid, time.Now(), enrollment_id).Error; err != nil {
+	if enrollment.Status != "Сформирован" {
 		tx.Rollback()
-		return err
+		return "", ErrEnrollmentProcessed
 	}
 
-	return tx.Commit().Error
+	if err := tx.Exec(`UPDATE public.enrollments SET status = ?, moderator_refer = ?, date_processed = ?, rejection_code = ?, rejection_comment = ? WHERE id = ?`,
+		new_status, moderator, time.Now(), rejection.ReasonCode, strings.TrimSpace(rejection.Comment), enrollment_id).Error; err != nil {
+		tx.Rollback()
+		return "", err
+	}
+
+	if err := emitEnrollmentEvent(tx, enrollment_id, new_status, reason); err != nil {
+		tx.Rollback()
+		return "", err
+	}
+
+	return new_status, tx.Commit().Error
 }
 
 func (r *Repository) UserConfirmEnrollment(uuid uuid.UUID, enrollment_id int) error {
@@ -276,7 +342,7 @@ func (r *Repository) UserConfirmEnrollment(uuid uuid.UUID, enrollment_id int) er
 
 func (r *Repository) FindGroup(group ds.Group) (ds.Group, error) {
 	var result ds.Group
-	err := r.db.Where(&group).Find(&result).Error
+	err := r.db.Joins("Coach").Preload("Course").Preload("Location").Preload("Sessions", orderSessions).Where(&group).Limit(1).Find(&result).Error
 	if err != nil {
 		return ds.Group{}, err
 	} else {
@@ -284,28 +350,141 @@ func (r *Repository) FindGroup(group ds.Group) (ds.Group, error) {
 	}
 }
 
-func (r *Repository) FindEnrollment(enrollment *ds.Enrollment) (ds.Enrollment, error) {
+func (r *Repository) FindEnrollment(enrollment *ds.Enrollment, expand ds.EnrollmentExpand) (ds.Enrollment, error) {
 	var result ds.Enrollment
-	err := r.db.Where(&enrollment).Find(&result).Error
+	err := preloadEnrollments(r.db, expand).Where(enrollment).Limit(1).Find(&result).Error
 	if err != nil {
 		return ds.Enrollment{}, err
 	}
 
-	var user ds.User
-	r.db.Where("uuid = ?", result.UserRefer).Find(&user)
+	if result.ID != 0 && (expand.Groups || expand.User) {
+		enrollments := []ds.Enrollment{result}
+		if expand.Groups {
+			err = r.loadEnrollmentGroups(enrollments)
+			if err != nil {
+				return ds.Enrollment{}, err
+			}
+		}
+		if expand.User {
+			err = r.loadEnrollmentProfiles(enrollments)
+			if err != nil {
+				return ds.Enrollment{}, err
+			}
+		}
+		result = enrollments[0]
+	}
 
-	result.User = user
+	return result, nil
+}
 
-	var moderator ds.User
-	r.db.Where("uuid = ?", result.ModeratorRefer).Find(&user)
+// EditGroup обновляет группу по названию. Если переданы занятия, они
+// полностью заменяют прежние: будущие занятия по старому расписанию без
+// отметок удаляются и создаются заново по новому. Расписание проверяется на
+// занятость мест с учётом ещё не изменённых полей группы.
+func (r *Repository) EditGroup(group *ds.Group) error {
+	tx := r.db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
 
-	result.Moderator = moderator
+	existing := ds.Group{}
+	if err := tx.Preload("Sessions").Where("title = ?", group.Title).Limit(1).Find(&existing).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
 
-	return result, nil
+	if existing.ID == 0 {
+		tx.Rollback()
+		return gorm.ErrRecordNotFound
+	}
+
+	status, term, location, sessions := existing.Status, existing.TermRefer, existing.LocationRefer, existing.Sessions
+	if group.Status != "" {
+		status = group.Status
+	}
+	if group.TermRefer != nil {
+		term = group.TermRefer
+	}
+	if group.LocationRefer != nil {
+		location = group.LocationRefer
+	}
+	if group.Sessions != nil {
+		sessions = group.Sessions
+	}
+
+	if err := checkGroupSchedule(tx, existing.ID, status, term, location, sessions); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	err := tx.Model(&ds.Group{}).Omit("Coach", "Course", "Location", "Term", "Sessions").Where("id = ?", existing.ID).Updates(group).Error
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	var regenerateTo time.Time
+	if group.Sessions != nil {
+		if err := tx.Where("group_refer = ?", existing.ID).Delete(&ds.GroupSession{}).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+
+		sessions = detachSessions(group.Sessions, existing.ID)
+		if len(sessions) > 0 {
+			if err := tx.Omit("Location").Create(&sessions).Error; err != nil {
+				tx.Rollback()
+				return err
+			}
+		}
+
+		regenerateTo, err = dropScheduledSessions(tx, int(existing.ID))
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+
+	if regenerateTo.IsZero() {
+		return nil
+	}
+
+	_, err = r.GenerateGroupSessions(int(existing.ID), time.Now(), regenerateTo)
+	return err
 }
 
-func (r *Repository) EditGroup(group *ds.Group) error {
-	return r.db.Model(&ds.Group{}).Where("title = ?", group.Title).Updates(group).Error
+// dropScheduledSessions удаляет будущие занятия группы, созданные по
+// расписанию, если на них ещё нет отметок, а у оставшихся занятий отвязывает
+// удалённые еженедельные занятия. Перенесённые занятия не трогаются.
+// Возвращает дату последнего удалённого занятия, чтобы создать занятия по
+// новому расписанию на тот же период.
+func dropScheduledSessions(tx *gorm.DB, group_id int) (time.Time, error) {
+	today := time.Now().Format("2006-01-02")
+	stale := tx.Model(&ds.SessionInstance{}).
+		Where("group_refer = ? AND date >= ? AND session_refer IS NOT NULL AND original_date IS NULL", group_id, today).
+		Where("NOT EXISTS (SELECT 1 FROM attendances WHERE attendances.session_instance_refer = session_instances.id)")
+
+	var last sql.NullTime
+	if err := stale.Session(&gorm.Session{}).Select("MAX(date)").Scan(&last).Error; err != nil {
+		return time.Time{}, err
+	}
+
+	if err := stale.Session(&gorm.Session{}).Delete(&ds.SessionInstance{}).Error; err != nil {
+		return time.Time{}, err
+	}
+
+	err := tx.Model(&ds.SessionInstance{}).Where("group_refer = ?", group_id).Update("session_refer", nil).Error
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return last.Time, nil
 }
 
 func (r *Repository) EditEnrollment(enrollment *ds.Enrollment) error {
@@ -316,22 +495,19 @@ func (r *Repository) SetGroupImage(id int, image string) error {
 	return r.db.Model(&ds.Group{}).Where("id = ?", id).Update("image_name", image).Error
 }
 
-func (r *Repository) Enroll(requestBody ds.EnrollRequestBody, userUUID uuid.UUID) error {
-	var group_ids []int
-	for _, groupTitle := range requestBody.Groups {
-		group_id, err := r.GetGroupID(groupTitle)
-		if err != nil {
-			return err
-		}
-		group_ids = append(group_ids, group_id)
-	}
-
+func (r *Repository) Enroll(group_ids []int, userUUID uuid.UUID) error {
 	enrollment := ds.Enrollment{}
 	enrollment.UserRefer = &userUUID
 	enrollment.DateCreated = time.Now()
-	enrollment.Status = requestBody.Status
+	enrollment.Status = "Черновик"
+
+	term, err := groupsTerm(r.db, group_ids, nil)
+	if err != nil {
+		return err
+	}
+	enrollment.TermRefer = term
 
-	err := r.db.Omit("moderator_refer", "date_processed", "date_finished").Create(&enrollment).Error
+	err = r.db.Omit("moderator_refer", "date_processed", "date_finished").Create(&enrollment).Error
 	if err != nil {
 		return err
 	}
@@ -372,27 +548,13 @@ func (r *Repository) GetEnrollmentToGroupAvailability(id int) (string, error) {
 }
 
 func (r *Repository) GetEnrollmentGroups(id int) ([]ds.Group, error) {
-	enrollment_to_groups := []ds.EnrollmentToGroup{}
+	groups := []ds.Group{}
 
-	err := r.db.Model(&ds.EnrollmentToGroup{}).Where("enrollment_refer = ?", id).Find(&enrollment_to_groups).Error
+	err := r.db.Where("id IN (?)", r.db.Model(&ds.EnrollmentToGroup{}).Select("group_refer").Where("enrollment_refer = ?", id)).Find(&groups).Error
 	if err != nil {
 		return []ds.Group{}, err
 	}
 
-	var groups []ds.Group
-	for _, enrollment_to_group := range enrollment_to_groups {
-		group, err := r.GetGroupByID(enrollment_to_group.GroupRefer)
-		if err != nil {
-			return []ds.Group{}, err
-		}
-		for _, ele := range groups {
-			if ele == *group {
-				continue
-			}
-		}
-		groups = append(groups, *group)
-	}
-
 	return groups, nil
 }
 
@@ -412,8 +574,24 @@ func (r *Repository) SetEnrollmentGroups(enrollmentID int, groups []string) erro
 		group_ids = append(group_ids, group_id)
 	}
 
+	enrollment := ds.Enrollment{}
+	if err := r.db.Select("id", "term_refer").First(&enrollment, "id = ?", enrollmentID).Error; err != nil {
+		return err
+	}
+
+	term, err := groupsTerm(r.db, group_ids, enrollment.TermRefer)
+	if err != nil {
+		return err
+	}
+
+	if enrollment.TermRefer == nil && term != nil {
+		if err := r.SetEnrollmentTerm(enrollmentID, *term); err != nil {
+			return err
+		}
+	}
+
 	var existing_links []ds.EnrollmentToGroup
-	err := r.db.Model(&ds.EnrollmentToGroup{}).Where("enrollment_refer = ?", enrollmentID).Find(&existing_links).Error
+	err = r.db.Model(&ds.EnrollmentToGroup{}).Where("enrollment_refer = ?", enrollmentID).Find(&existing_links).Error
 	if err != nil {
 		return err
 	}
@@ -470,8 +648,36 @@ func (r *Repository) DeleteEnrollmentToGroup(enrollment_id int, group_id int) er
 	return r.db.Where("enrollment_refer = ?", enrollment_id).Where("group_refer = ?", group_id).Delete(&ds.EnrollmentToGroup{}).Error
 }
 
+// ChangeEnrollmentToGroupAvailability меняет доступность группы в записи. Если
+// студента, которому при распределении не хватило мест, зачисляют в группу,
+// ему приходит уведомление.
 func (r *Repository) ChangeEnrollmentToGroupAvailability(enrollment_to_group *ds.EnrollmentToGroup) error {
-	return r.db.Model(&ds.EnrollmentToGroup{}).Where("id = ?", enrollment_to_group.ID).Updates(enrollment_to_group).Error
+	tx := r.db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	link := ds.EnrollmentToGroup{}
+	if err := tx.First(&link, "id = ?", enrollment_to_group.ID).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Model(&ds.EnrollmentToGroup{}).Where("id = ?", enrollment_to_group.ID).Updates(enrollment_to_group).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if link.Availability == ds.AvailabilityNoSeats && enrollment_to_group.Availability == ds.AvailabilityAssigned {
+		if err := emitWaitlistEvent(tx, link); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit().Error
 }
 
 func (r *Repository) Register(user *ds.User) error {
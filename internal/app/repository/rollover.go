@@ -0,0 +1,233 @@
+package repository
+
+import (
+	"errors"
+	"slices"
+	"strings"
+
+	"sports_courses/internal/app/ds"
+)
+
+var (
+	ErrRolloverSameTerm     = errors.New("семестры переноса должны различаться")
+	ErrRolloverGroupMissing = errors.New("не все группы найдены в исходном семестре")
+	ErrRolloverFailed       = errors.New("перенос не выполнен: у части групп есть ошибки")
+)
+
+// RolloverGroups копирует группы из одного семестра в другой вместе с
+// занятиями, тренером, местом, вместимостью и галереей. Счётчик записанных
+// обнуляется. Перенос выполняется целиком или не выполняется вовсе: если хотя
+// бы у одной копии занято место или совпало название, возвращается отчёт и
+// ErrRolloverFailed. При DryRun все проверки проходят в транзакции, которая
+// затем откатывается.
+func (r *Repository) RolloverGroups(request ds.RolloverRequestBody) (ds.RolloverReport, error) {
+	report := ds.RolloverReport{DryRun: request.DryRun, Groups: []ds.RolloverResult{}}
+
+	if request.FromTermID == request.ToTermID {
+		return report, ErrRolloverSameTerm
+	}
+
+	from, err := r.GetTermByID(int(request.FromTermID))
+	if err != nil {
+		return report, err
+	}
+
+	to, err := r.GetTermByID(int(request.ToTermID))
+	if err != nil {
+		return report, err
+	}
+
+	sources := []ds.Group{}
+	query := r.db.Preload("Sessions", orderSessions).Where("term_refer = ?", from.ID)
+	if len(request.GroupIDs) > 0 {
+		query = query.Where("id IN ?", request.GroupIDs)
+	} else {
+		query = query.Where("status = ?", ActiveGroupStatus)
+	}
+
+	if err := query.Order("id").Find(&sources).Error; err != nil {
+		return report, err
+	}
+
+	if len(request.GroupIDs) > 0 && len(sources) != len(uniqueIDs(request.GroupIDs)) {
+		return report, ErrRolloverGroupMissing
+	}
+
+	overrides := make(map[uint]ds.GroupRolloverOverride, len(request.Overrides))
+	for _, override := range request.Overrides {
+		overrides[override.GroupID] = override
+	}
+
+	tx := r.db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := lockSchedule(tx); err != nil {
+		tx.Rollback()
+		return report, err
+	}
+
+	failed := false
+	for _, source := range sources {
+		clone := cloneGroup(source, from, to, overrides[source.ID])
+		result := ds.RolloverResult{SourceID: source.ID, SourceTitle: source.Title}
+
+		var taken int64
+		if err := tx.Model(&ds.Group{}).Where("title = ?", clone.Title).Count(&taken).Error; err != nil {
+			tx.Rollback()
+			return report, err
+		}
+
+		if taken > 0 {
+			result.Error = "группа с названием \"" + clone.Title + "\" уже существует"
+		} else if !isInactiveGroupStatus(clone.Status) {
+			result.Conflicts, err = findLocationConflicts(tx, 0, clone.TermRefer, clone.LocationRefer, clone.Sessions)
+			if err != nil {
+				tx.Rollback()
+				return report, err
+			}
+		}
+
+		if result.Error != "" || len(result.Conflicts) > 0 {
+			failed = true
+			result.Group = clone
+			report.Groups = append(report.Groups, result)
+			continue
+		}
+
+		clone.Sessions = detachSessions(clone.Sessions, 0)
+		if err := tx.Omit("Coach", "Course", "Location", "Term").Create(&clone).Error; err != nil {
+			tx.Rollback()
+			return report, err
+		}
+
+		images := []ds.GroupImage{}
+		if err := tx.Where("group_refer = ?", source.ID).Order("position").Find(&images).Error; err != nil {
+			tx.Rollback()
+			return report, err
+		}
+
+		for _, image := range images {
+			// объекты в хранилище общие с исходной группой, копируются только записи
+			image.ID = 0
+			image.GroupRefer = int(clone.ID)
+			if err := tx.Omit("Group").Create(&image).Error; err != nil {
+				tx.Rollback()
+				return report, err
+			}
+		}
+
+		result.Group = clone
+		result.Images = len(images)
+		report.Groups = append(report.Groups, result)
+		report.Created++
+	}
+
+	if failed || request.DryRun {
+		if err := tx.Rollback().Error; err != nil {
+			return report, err
+		}
+
+		// в отчёте не должно быть id из откаченной транзакции
+		for i := range report.Groups {
+			report.Groups[i].Group.ID = 0
+			for j := range report.Groups[i].Group.Sessions {
+				report.Groups[i].Group.Sessions[j].ID = 0
+				report.Groups[i].Group.Sessions[j].GroupRefer = 0
+			}
+		}
+
+		if failed {
+			report.Created = 0
+			return report, ErrRolloverFailed
+		}
+
+		return report, nil
+	}
+
+	return report, tx.Commit().Error
+}
+
+// cloneGroup собирает копию группы source для семестра to.
+func cloneGroup(source ds.Group, from *ds.Term, to *ds.Term, override ds.GroupRolloverOverride) ds.Group {
+	clone := ds.Group{
+		Title:         rolloverTitle(source.Title, from.Name, to.Name),
+		CourseRefer:   source.CourseRefer,
+		TermRefer:     &to.ID,
+		Schedule:      source.Schedule,
+		LocationRefer: source.LocationRefer,
+		Status:        source.Status,
+		CoachRefer:    source.CoachRefer,
+		Capacity:      source.Capacity,
+		Enrolled:      "0",
+		Description:   source.Description,
+		ImageName:     source.ImageName,
+		Sessions:      detachSessions(source.Sessions, 0),
+
+		RequiresClearance: source.RequiresClearance,
+	}
+
+	if override.Title != "" {
+		clone.Title = strings.TrimSpace(override.Title)
+	}
+	if override.Schedule != "" {
+		clone.Schedule = override.Schedule
+	}
+	if override.Capacity != "" {
+		clone.Capacity = override.Capacity
+	}
+	if override.CoachRefer != nil {
+		clone.CoachRefer = override.CoachRefer
+	}
+	if override.LocationRefer != nil {
+		clone.LocationRefer = override.LocationRefer
+	}
+	if override.Sessions != nil {
+		clone.Sessions = detachSessions(override.Sessions, 0)
+	}
+
+	return clone
+}
+
+// rolloverTitle заменяет в названии группы название старого семестра на новое,
+// а если его там нет - дописывает новое в скобках, чтобы названия не совпали.
+func rolloverTitle(title string, from string, to string) string {
+	if from != "" && strings.Contains(title, from) {
+		return strings.ReplaceAll(title, from, to)
+	}
+
+	return title + " (" + to + ")"
+}
+
+func uniqueIDs(ids []uint) []uint {
+	result := slices.Clone(ids)
+	slices.Sort(result)
+	return slices.Compact(result)
+}
+
+// UnusedGroupImageNames оставляет из names объекты, на которые больше не
+// ссылается ни одна картинка галереи. После переноса групп в новый семестр
+// копии ссылаются на те же объекты, что и исходные группы.
+func (r *Repository) UnusedGroupImageNames(names []string) ([]string, error) {
+	if len(names) == 0 {
+		return names, nil
+	}
+
+	used := []string{}
+	err := r.db.Model(&ds.GroupImage{}).Where("image_name IN ?", names).Distinct().Pluck("image_name", &used).Error
+	if err != nil {
+		return nil, err
+	}
+
+	unused := make([]string, 0, len(names))
+	for _, name := range names {
+		if !slices.Contains(used, name) {
+			unused = append(unused, name)
+		}
+	}
+
+	return unused, nil
+}
@@ -0,0 +1,312 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"sports_courses/internal/app/ds"
+)
+
+var ErrInvalidRule = errors.New("правило задано некорректно")
+
+var RuleSortColumns = []string{"id", "kind"}
+
+var EnrollmentRuleKinds = []string{
+	ds.RuleMaxGroupsPerTerm,
+	ds.RuleMaxGroupsPerCourse,
+	ds.RuleMedicalGroup,
+	ds.RuleYearOfStudy,
+	ds.RuleGender,
+}
+
+// ActiveEnrollmentStatuses - статусы записей, группы которых учитываются в
+// лимитах студента.
+var ActiveEnrollmentStatuses = []string{"Черновик", "Сформирован", "Завершён"}
+
+func validateRule(rule *ds.EnrollmentRule) error {
+	switch rule.Kind {
+	case ds.RuleMaxGroupsPerTerm, ds.RuleMaxGroupsPerCourse:
+		if rule.MaxGroups <= 0 {
+			return fmt.Errorf("%w: MaxGroups должен быть больше нуля", ErrInvalidRule)
+		}
+	case ds.RuleMedicalGroup, ds.RuleGender:
+		if len(rule.Values) == 0 {
+			return fmt.Errorf("%w: нужно перечислить допустимые значения в Values", ErrInvalidRule)
+		}
+	case ds.RuleYearOfStudy:
+		if rule.MinYear <= 0 && rule.MaxYear <= 0 || rule.MaxYear > 0 && rule.MinYear > rule.MaxYear {
+			return fmt.Errorf("%w: нужно задать MinYear и/или MaxYear", ErrInvalidRule)
+		}
+	default:
+		return fmt.Errorf("%w: неизвестный вид правила %q", ErrInvalidRule, rule.Kind)
+	}
+
+	return nil
+}
+
+func (r *Repository) CreateRule(rule *ds.EnrollmentRule) error {
+	if err := validateRule(rule); err != nil {
+		return err
+	}
+
+	return r.db.Create(rule).Error
+}
+
+// EditRule полностью заменяет правило: частичное обновление не смогло бы
+// выключить правило или снять ограничение по семестру.
+func (r *Repository) EditRule(rule *ds.EnrollmentRule) error {
+	if err := validateRule(rule); err != nil {
+		return err
+	}
+
+	if _, err := r.GetRuleByID(int(rule.ID)); err != nil {
+		return err
+	}
+
+	return r.db.Save(rule).Error
+}
+
+func (r *Repository) GetRuleByID(id int) (*ds.EnrollmentRule, error) {
+	rule := &ds.EnrollmentRule{}
+
+	err := r.db.First(rule, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return rule, nil
+}
+
+func (r *Repository) GetRules(page ds.PageRequest) ([]ds.EnrollmentRule, ds.PageInfo, error) {
+	rules := []ds.EnrollmentRule{}
+
+	if len(page.Sort) == 0 {
+		page.Sort = []ds.SortField{{Column: "id"}}
+	}
+
+	info, err := paginate(r.db.Model(&ds.EnrollmentRule{}), page, &rules)
+	if err != nil {
+		return nil, ds.PageInfo{}, err
+	}
+
+	return rules, info, nil
+}
+
+func (r *Repository) DeleteRule(id int) error {
+	return r.db.Delete(&ds.EnrollmentRule{}, id).Error
+}
+
+// GetStudentProfile возвращает профиль студента или nil, если он не заполнен.
+func (r *Repository) GetStudentProfile(userUUID uuid.UUID) (*ds.StudentProfile, error) {
+	profiles := []ds.StudentProfile{}
+
+	err := r.db.Where("user_refer = ?", userUUID).Limit(1).Find(&profiles).Error
+	if err != nil || len(profiles) == 0 {
+		return nil, err
+	}
+
+	return &profiles[0], nil
+}
+
+type ruleGroup struct {
+	ID          uint
+	Title       string
+	CourseRefer *uint
+	TermRefer   *uint
+}
+
+// CheckEnrollmentRules проверяет, что запись enrollment_id с группами
+// group_ids не нарушает включённых правил. Лимиты считаются вместе с группами
+// других активных записей того же студента. Возвращаются все нарушения сразу.
+func (r *Repository) CheckEnrollmentRules(enrollment_id int, group_ids []int) ([]ds.RuleViolation, error) {
+	if len(group_ids) == 0 {
+		return []ds.RuleViolation{}, nil
+	}
+
+	enrollment := ds.Enrollment{}
+	if err := r.db.Select("id", "user_refer", "term_refer").First(&enrollment, "id = ?", enrollment_id).Error; err != nil {
+		return nil, err
+	}
+
+	return r.checkRules(enrollment, group_ids)
+}
+
+// CheckNewEnrollmentRules проверяет правила для записи студента userUUID,
+// которая ещё не создана.
+func (r *Repository) CheckNewEnrollmentRules(userUUID uuid.UUID, group_ids []int) ([]ds.RuleViolation, error) {
+	return r.checkRules(ds.Enrollment{UserRefer: &userUUID}, group_ids)
+}
+
+func (r *Repository) checkRules(enrollment ds.Enrollment, group_ids []int) ([]ds.RuleViolation, error) {
+	violations := []ds.RuleViolation{}
+
+	rules := []ds.EnrollmentRule{}
+	if err := r.db.Where("enabled = ?", true).Order("id").Find(&rules).Error; err != nil {
+		return nil, err
+	}
+
+	if len(rules) == 0 || len(group_ids) == 0 {
+		return violations, nil
+	}
+
+	proposed := []ruleGroup{}
+	err := r.db.Model(&ds.Group{}).Select("id, title, course_refer, term_refer").Where("id IN ?", group_ids).Order("id").Scan(&proposed).Error
+	if err != nil {
+		return nil, err
+	}
+
+	others := []ruleGroup{}
+	err = r.db.Model(&ds.Group{}).Select("id, title, course_refer, term_refer").
+		Where("id IN (?)", r.db.Model(&ds.EnrollmentToGroup{}).Select("enrollment_to_groups.group_refer").
+			Joins("JOIN enrollments ON enrollments.id = enrollment_to_groups.enrollment_refer").
+			Where("enrollments.user_refer = ? AND enrollments.status IN ? AND enrollments.id <> ?", enrollment.UserRefer, ActiveEnrollmentStatuses, enrollment.ID)).
+		Where("id NOT IN ?", group_ids).
+		Scan(&others).Error
+	if err != nil {
+		return nil, err
+	}
+
+	profile, err := r.GetStudentProfile(*enrollment.UserRefer)
+	if err != nil {
+		return nil, err
+	}
+
+	// группы без семестра считаются группами семестра записи, иначе они
+	// обходили бы лимит на семестр
+	term := enrollment.TermRefer
+	for _, group := range proposed {
+		if term == nil && group.TermRefer != nil {
+			term = group.TermRefer
+		}
+	}
+	if term == nil {
+		term = new(uint)
+	}
+
+	all := append(slices.Clone(proposed), others...)
+	for i := range all {
+		if all[i].TermRefer == nil {
+			all[i].TermRefer = term
+		}
+	}
+	proposed = all[:len(proposed)]
+
+	for _, rule := range rules {
+		switch rule.Kind {
+		case ds.RuleMaxGroupsPerTerm:
+			violations = append(violations, checkGroupLimit(rule, proposed, all, func(group ruleGroup) *uint { return group.TermRefer }, "семестре")...)
+		case ds.RuleMaxGroupsPerCourse:
+			violations = append(violations, checkGroupLimit(rule, proposed, all, func(group ruleGroup) *uint { return group.CourseRefer }, "курсе")...)
+		default:
+			for _, group := range proposed {
+				if !ruleApplies(rule, group) {
+					continue
+				}
+
+				if message := checkStudent(rule, profile); message != "" {
+					violations = append(violations, ds.RuleViolation{
+						RuleID:  rule.ID,
+						Kind:    rule.Kind,
+						GroupID: group.ID,
+						Message: "Группа \"" + group.Title + "\": " + message,
+					})
+				}
+			}
+		}
+	}
+
+	return violations, nil
+}
+
+func ruleApplies(rule ds.EnrollmentRule, group ruleGroup) bool {
+	if rule.GroupRefer != nil && *rule.GroupRefer != group.ID {
+		return false
+	}
+
+	if rule.CourseRefer != nil && (group.CourseRefer == nil || *rule.CourseRefer != *group.CourseRefer) {
+		return false
+	}
+
+	if rule.TermRefer != nil && (group.TermRefer == nil || *rule.TermRefer != *group.TermRefer) {
+		return false
+	}
+
+	return true
+}
+
+// checkGroupLimit считает группы студента отдельно по каждому значению key
+// (семестру или курсу) и сообщает о превышении только там, куда попадает хотя
+// бы одна из добавляемых групп.
+func checkGroupLimit(rule ds.EnrollmentRule, proposed []ruleGroup, all []ruleGroup, key func(ruleGroup) *uint, scope string) []ds.RuleViolation {
+	violations := []ds.RuleViolation{}
+
+	counts := map[uint]int{}
+	for _, group := range all {
+		if value := key(group); value != nil && ruleApplies(rule, group) {
+			counts[*value]++
+		}
+	}
+
+	reported := map[uint]bool{}
+	for _, group := range proposed {
+		value := key(group)
+		if value == nil || !ruleApplies(rule, group) || reported[*value] || counts[*value] <= rule.MaxGroups {
+			continue
+		}
+
+		reported[*value] = true
+		violations = append(violations, ds.RuleViolation{
+			RuleID:  rule.ID,
+			Kind:    rule.Kind,
+			GroupID: group.ID,
+			Message: fmt.Sprintf("В одном %s можно записаться не больше чем в %d групп(ы), выбрано %d", scope, rule.MaxGroups, counts[*value]),
+		})
+	}
+
+	return violations
+}
+
+// checkStudent проверяет правило, относящееся к самому студенту, и
+// возвращает текст нарушения или пустую строку.
+func checkStudent(rule ds.EnrollmentRule, profile *ds.StudentProfile) string {
+	switch rule.Kind {
+	case ds.RuleMedicalGroup:
+		if profile == nil || profile.MedicalGroup == "" {
+			return "нужно указать медицинскую группу в профиле"
+		}
+		if !slices.Contains(rule.Values, profile.MedicalGroup) {
+			return "группа доступна только для медицинских групп: " + strings.Join(rule.Values, ", ")
+		}
+	case ds.RuleGender:
+		if profile == nil || profile.Gender == "" {
+			return "нужно указать пол в профиле"
+		}
+		if !slices.Contains(rule.Values, profile.Gender) {
+			return "группа доступна только для пола: " + strings.Join(rule.Values, ", ")
+		}
+	case ds.RuleYearOfStudy:
+		if profile == nil || profile.Year == 0 {
+			return "нужно указать курс обучения в профиле"
+		}
+		if rule.MinYear > 0 && profile.Year < rule.MinYear || rule.MaxYear > 0 && profile.Year > rule.MaxYear {
+			return fmt.Sprintf("группа доступна студентам %s", yearRange(rule.MinYear, rule.MaxYear))
+		}
+	}
+
+	return ""
+}
+
+func yearRange(min int, max int) string {
+	switch {
+	case min > 0 && max > 0:
+		return fmt.Sprintf("с %d по %d курс", min, max)
+	case min > 0:
+		return fmt.Sprintf("начиная с %d курса", min)
+	default:
+		return fmt.Sprintf("не старше %d курса", max)
+	}
+}
@@ -0,0 +1,211 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"sports_courses/internal/app/ds"
+)
+
+var (
+	ErrSessionCancelled = errors.New("занятие отменено")
+	ErrSessionPast      = errors.New("прошедшее занятие нельзя изменить")
+	ErrSessionSlotTaken = errors.New("у группы уже есть занятие в это время")
+)
+
+// CancelSessions отменяет занятия и сообщает об этом зачисленным студентам.
+// Прошедшие и уже отменённые занятия не трогаются. Возвращает отменённые занятия.
+func (r *Repository) CancelSessions(request ds.CancelSessionsRequestBody) ([]ds.SessionInstance, error) {
+	tx := r.db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	instances := []ds.SessionInstance{}
+	query := tx.Preload("Location").Where("cancelled = ? AND date >= CURRENT_DATE", false)
+	if request.SessionID != 0 {
+		query = query.Where("id = ?", request.SessionID)
+	} else {
+		query = query.Where("date BETWEEN ? AND ?", request.From.Format("2006-01-02"), request.To.Format("2006-01-02"))
+		if request.GroupID != 0 {
+			query = query.Where("group_refer = ?", request.GroupID)
+		}
+	}
+
+	if err := query.Order("date, start_time").Find(&instances).Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if request.SessionID != 0 && len(instances) == 0 {
+		instance := ds.SessionInstance{}
+		err := tx.First(&instance, "id = ?", request.SessionID).Error
+		tx.Rollback()
+		if err != nil {
+			return nil, err
+		}
+		if instance.Cancelled {
+			return nil, ErrSessionCancelled
+		}
+		return nil, ErrSessionPast
+	}
+
+	for i := range instances {
+		instances[i].Cancelled = true
+		instances[i].Reason = request.Reason
+
+		err := tx.Model(&ds.SessionInstance{}).Where("id = ?", instances[i].ID).Updates(map[string]interface{}{
+			"cancelled": true,
+			"reason":    request.Reason,
+		}).Error
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+
+		if err := emitSessionEvent(tx, ds.EventSessionCancelled, instances[i], nil); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
+	return instances, tx.Commit().Error
+}
+
+// MoveSession переносит занятие на другое время, день или место и сообщает
+// об этом зачисленным студентам.
+func (r *Repository) MoveSession(request ds.MoveSessionRequestBody) (*ds.SessionInstance, error) {
+	tx := r.db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	instance := ds.SessionInstance{}
+	if err := tx.First(&instance, "id = ?", request.SessionID).Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if instance.Cancelled {
+		tx.Rollback()
+		return nil, ErrSessionCancelled
+	}
+
+	today := time.Now().Format("2006-01-02")
+	if instance.Date.Format("2006-01-02") < today || request.Date.Format("2006-01-02") < today {
+		tx.Rollback()
+		return nil, ErrSessionPast
+	}
+
+	var taken int64
+	err := tx.Model(&ds.SessionInstance{}).
+		Where("group_refer = ? AND date = ? AND start_time = ? AND id <> ?", instance.GroupRefer, request.Date.Format("2006-01-02"), request.StartTime, instance.ID).
+		Count(&taken).Error
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if taken > 0 {
+		tx.Rollback()
+		return nil, ErrSessionSlotTaken
+	}
+
+	previous := instance
+	if instance.OriginalDate == nil {
+		originalDate := instance.Date
+		instance.OriginalDate = &originalDate
+		instance.OriginalStartTime = instance.StartTime
+	}
+
+	instance.Date = time.Date(request.Date.Year(), request.Date.Month(), request.Date.Day(), 0, 0, 0, 0, time.UTC)
+	instance.StartTime = request.StartTime
+	instance.EndTime = request.EndTime
+	instance.Reason = request.Reason
+	if request.LocationID != nil {
+		instance.LocationRefer = request.LocationID
+	}
+
+	err = tx.Model(&ds.SessionInstance{}).Where("id = ?", instance.ID).Updates(map[string]interface{}{
+		"date":                instance.Date,
+		"start_time":          instance.StartTime,
+		"end_time":            instance.EndTime,
+		"location_refer":      instance.LocationRefer,
+		"reason":              instance.Reason,
+		"original_date":       instance.OriginalDate,
+		"original_start_time": instance.OriginalStartTime,
+	}).Error
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := emitSessionEvent(tx, ds.EventSessionMoved, instance, &previous); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Preload("Location").First(&instance, "id = ?", instance.ID).Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	return &instance, tx.Commit().Error
+}
+
+// emitSessionEvent пишет событие об изменении занятия каждому студенту,
+// зачисленному в его группу.
+func emitSessionEvent(tx *gorm.DB, eventType string, instance ds.SessionInstance, previous *ds.SessionInstance) error {
+	group := ds.Group{}
+	if err := tx.Select("id", "title").First(&group, "id = ?", instance.GroupRefer).Error; err != nil {
+		return err
+	}
+
+	payload := map[string]interface{}{
+		"GroupID":    group.ID,
+		"GroupTitle": group.Title,
+		"SessionID":  instance.ID,
+		"Date":       instance.Date.Format("2006-01-02"),
+		"StartTime":  instance.StartTime,
+		"EndTime":    instance.EndTime,
+		"Reason":     instance.Reason,
+	}
+
+	if previous != nil {
+		payload["PreviousDate"] = previous.Date.Format("2006-01-02")
+		payload["PreviousStartTime"] = previous.StartTime
+	}
+
+	return emitGroupEvent(tx, instance.GroupRefer, eventType, payload)
+}
+
+// GetUserSchedule возвращает занятия групп, в которые зачислен пользователь,
+// за период, вместе с отменёнными и перенесёнными.
+func (r *Repository) GetUserSchedule(userUUID uuid.UUID, from, to time.Time) ([]ds.ScheduleEntry, error) {
+	entries := []ds.ScheduleEntry{}
+
+	err := r.db.Table("session_instances").
+		Select(`session_instances.id AS session_id, groups.id AS group_id, groups.title AS group_title,
+			session_instances.date, session_instances.start_time, session_instances.end_time,
+			COALESCE(locations.building, '') AS building, COALESCE(locations.room, '') AS room,
+			session_instances.cancelled, session_instances.reason,
+			session_instances.original_date, COALESCE(session_instances.original_start_time, '') AS original_start_time`).
+		Joins("JOIN groups ON groups.id = session_instances.group_refer").
+		Joins("LEFT JOIN locations ON locations.id = session_instances.location_refer").
+		Where("session_instances.group_refer IN (?)", memberGroups(r.db, userUUID)).
+		Where("session_instances.date BETWEEN ? AND ?", from.Format("2006-01-02"), to.Format("2006-01-02")).
+		Order("session_instances.date, session_instances.start_time").
+		Scan(&entries).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
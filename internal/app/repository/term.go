@@ -0,0 +1,191 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"sports_courses/internal/app/ds"
+)
+
+var (
+	ErrInvalidTermDates = errors.New("даты семестра заданы некорректно")
+	ErrTermMismatch     = errors.New("в одной записи могут быть только группы одного семестра")
+)
+
+var TermSortColumns = []string{"id", "name", "start_date"}
+
+func (r *Repository) CreateTerm(term *ds.Term) error {
+	if err := validateTerm(term); err != nil {
+		return err
+	}
+
+	return r.db.Create(term).Error
+}
+
+func (r *Repository) EditTerm(term *ds.Term) error {
+	existing, err := r.GetTermByID(int(term.ID))
+	if err != nil {
+		return err
+	}
+
+	merged := *existing
+	if term.Name != "" {
+		merged.Name = term.Name
+	}
+	if !term.StartDate.IsZero() {
+		merged.StartDate = term.StartDate
+	}
+	if !term.EndDate.IsZero() {
+		merged.EndDate = term.EndDate
+	}
+	if !term.EnrollmentOpensAt.IsZero() {
+		merged.EnrollmentOpensAt = term.EnrollmentOpensAt
+	}
+	if !term.EnrollmentClosesAt.IsZero() {
+		merged.EnrollmentClosesAt = term.EnrollmentClosesAt
+	}
+
+	if err := validateTerm(&merged); err != nil {
+		return err
+	}
+
+	return r.db.Save(&merged).Error
+}
+
+func validateTerm(term *ds.Term) error {
+	if term.StartDate.IsZero() || term.EndDate.IsZero() || !term.StartDate.Before(term.EndDate) {
+		return ErrInvalidTermDates
+	}
+
+	if term.EnrollmentOpensAt.IsZero() || term.EnrollmentClosesAt.IsZero() || !term.EnrollmentOpensAt.Before(term.EnrollmentClosesAt) {
+		return ErrInvalidTermDates
+	}
+
+	return nil
+}
+
+func (r *Repository) GetTermByID(id int) (*ds.Term, error) {
+	term := &ds.Term{}
+
+	err := r.db.First(term, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return term, nil
+}
+
+func (r *Repository) GetTerms(page ds.PageRequest) ([]ds.Term, ds.PageInfo, error) {
+	terms := []ds.Term{}
+
+	if len(page.Sort) == 0 {
+		page.Sort = []ds.SortField{{Column: "start_date", Desc: true}}
+	}
+
+	info, err := paginate(r.db.Model(&ds.Term{}), page, &terms)
+	if err != nil {
+		return nil, ds.PageInfo{}, err
+	}
+
+	return terms, info, nil
+}
+
+// GetCurrentTerm возвращает семестр, который идёт сейчас. Если их несколько,
+// берётся начавшийся позже всех. Если ни один не идёт, возвращается nil.
+func (r *Repository) GetCurrentTerm() (*ds.Term, error) {
+	terms := []ds.Term{}
+
+	now := time.Now()
+	err := r.db.Where("start_date <= ? AND ? <= end_date", now, now).Order("start_date DESC").Limit(1).Find(&terms).Error
+	if err != nil || len(terms) == 0 {
+		return nil, err
+	}
+
+	return &terms[0], nil
+}
+
+// GetCatalogueTerm возвращает семестр, на который сейчас идёт запись, а если
+// запись никуда не идёт - текущий семестр. Этот семестр каталог и списки
+// записей показывают по умолчанию.
+func (r *Repository) GetCatalogueTerm() (*ds.Term, error) {
+	terms := []ds.Term{}
+
+	now := time.Now()
+	err := r.db.Where("enrollment_opens_at <= ? AND ? < enrollment_closes_at", now, now).Order("start_date DESC").Limit(1).Find(&terms).Error
+	if err != nil {
+		return nil, err
+	}
+
+	if len(terms) == 0 {
+		return r.GetCurrentTerm()
+	}
+
+	return &terms[0], nil
+}
+
+// GetGroupTerm возвращает семестр группы или nil, если группа не привязана к семестру.
+func (r *Repository) GetGroupTerm(group_id int) (*ds.Term, error) {
+	terms := []ds.Term{}
+
+	err := r.db.Where("id = (?)", r.db.Model(&ds.Group{}).Select("term_refer").Where("id = ?", group_id)).Limit(1).Find(&terms).Error
+	if err != nil || len(terms) == 0 {
+		return nil, err
+	}
+
+	return &terms[0], nil
+}
+
+// GetEnrollmentTerm возвращает семестр записи или nil, если запись не привязана к семестру.
+func (r *Repository) GetEnrollmentTerm(enrollment_id int) (*ds.Term, error) {
+	terms := []ds.Term{}
+
+	err := r.db.Where("id = (?)", r.db.Model(&ds.Enrollment{}).Select("term_refer").Where("id = ?", enrollment_id)).Limit(1).Find(&terms).Error
+	if err != nil || len(terms) == 0 {
+		return nil, err
+	}
+
+	return &terms[0], nil
+}
+
+// SetEnrollmentTerm привязывает запись к семестру, если она ещё ни к какому не
+// привязана или в ней нет групп. Если в записи уже есть группы другого
+// семестра, возвращается ErrTermMismatch.
+func (r *Repository) SetEnrollmentTerm(enrollment_id int, term_id uint) error {
+	result := r.db.Model(&ds.Enrollment{}).
+		Where("id = ?", enrollment_id).
+		Where("term_refer IS NULL OR term_refer = ? OR NOT EXISTS (SELECT 1 FROM enrollment_to_groups WHERE enrollment_to_groups.enrollment_refer = enrollments.id)", term_id).
+		Update("term_refer", term_id)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrTermMismatch
+	}
+
+	return nil
+}
+
+// groupsTerm возвращает семестр, к которому относятся группы group_ids, или
+// term, если у групп семестра нет. Группы без семестра подходят к любому.
+// Если группы из разных семестров или не из term, возвращается ErrTermMismatch.
+func groupsTerm(db *gorm.DB, group_ids []int, term *uint) (*uint, error) {
+	term_ids := []uint{}
+	err := db.Model(&ds.Group{}).Where("id IN ? AND term_refer IS NOT NULL", group_ids).Distinct().Pluck("term_refer", &term_ids).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, term_id := range term_ids {
+		if term != nil && *term != term_id {
+			return nil, ErrTermMismatch
+		}
+
+		term_id := term_id
+		term = &term_id
+	}
+
+	return term, nil
+}
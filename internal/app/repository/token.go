@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"time"
+
+	"gorm.io/gorm/clause"
+
+	"sports_courses/internal/app/ds"
+)
+
+// RevokeToken сохраняет отзыв токена до expiresAt.
+func (r *Repository) RevokeToken(token string, expiresAt time.Time) error {
+	return r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&ds.RevokedToken{Token: token, ExpiresAt: expiresAt}).Error
+}
+
+func (r *Repository) IsTokenRevoked(token string) (bool, error) {
+	var count int64
+	err := r.db.Model(&ds.RevokedToken{}).Where("token = ? AND expires_at > ?", token, time.Now()).Count(&count).Error
+
+	return count > 0, err
+}
+
+// GetUnsyncedRevocations возвращает действующие отзывы, ещё не записанные в redis.
+func (r *Repository) GetUnsyncedRevocations(limit int) ([]ds.RevokedToken, error) {
+	tokens := []ds.RevokedToken{}
+	err := r.db.Where("NOT synced AND expires_at > ?", time.Now()).Limit(limit).Find(&tokens).Error
+
+	return tokens, err
+}
+
+func (r *Repository) MarkRevocationSynced(token string) error {
+	return r.db.Model(&ds.RevokedToken{}).Where("token = ?", token).Update("synced", true).Error
+}
+
+// DeleteExpiredRevocations удаляет отзывы токенов, срок которых уже истёк.
+func (r *Repository) DeleteExpiredRevocations() error {
+	return r.db.Where("expires_at <= ?", time.Now()).Delete(&ds.RevokedToken{}).Error
+}
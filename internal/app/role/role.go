@@ -7,4 +7,5 @@ const (
 	User
 	Moderator
 	Admin
+	Coach
 )
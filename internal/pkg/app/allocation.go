@@ -0,0 +1,157 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"sports_courses/internal/app/ds"
+	"sports_courses/internal/app/repository"
+	"sports_courses/internal/app/role"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// @Summary      Задать предпочтения групп
+// @Description  Расставляет группы записи в порядке предпочтения: первая - самая желанная. Нужно перечислить все группы записи
+// @Tags         Запись
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  string
+// @Param request_body body ds.SetPreferencesRequestBody true "Группы в порядке предпочтения"
+// @Router       /enrollment/preferences [put]
+func (a *Application) set_enrollment_preferences(c *gin.Context) {
+	var requestBody ds.SetPreferencesRequestBody
+
+	if err := c.BindJSON(&requestBody); err != nil || requestBody.EnrollmentID == 0 {
+		c.String(http.StatusBadRequest, "Не получается распознать json запрос")
+		return
+	}
+
+	_userUUID, _ := c.Get("userUUID")
+	_userRole, _ := c.Get("role")
+
+	userUUID := _userUUID.(uuid.UUID)
+	userRole := _userRole.(role.Role)
+
+	enrollment, err := a.repo.FindEnrollment(&ds.Enrollment{ID: uint(requestBody.EnrollmentID)}, ds.EnrollmentExpand{})
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	if enrollment.ID == 0 {
+		c.String(http.StatusNotFound, "Запись не найдена")
+		return
+	}
+
+	if userRole == role.User && (enrollment.UserRefer == nil || *enrollment.UserRefer != userUUID) {
+		c.String(http.StatusForbidden, "Можно менять предпочтения только своей записи")
+		return
+	}
+
+	err = a.repo.SetEnrollmentPreferences(requestBody.EnrollmentID, requestBody.GroupIDs)
+	if errors.Is(err, repository.ErrPreferencesMismatch) || errors.Is(err, repository.ErrEnrollmentProcessed) {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.String(http.StatusOK, "Предпочтения успешно сохранены")
+}
+
+// @Summary      Распределить места семестра
+// @Description  Проводит жеребьёвку среди сформированных записей семестра и распределяет места в группах по предпочтениям студентов. С тем же Seed результат повторяется, с DryRun ничего не сохраняется
+// @Tags         Семестры
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  ds.AllocationReport
+// @Param request_body body ds.AllocationRequestBody true "Параметры распределения"
+// @Router       /term/allocate [post]
+func (a *Application) allocate_term(c *gin.Context) {
+	var requestBody ds.AllocationRequestBody
+
+	if err := c.BindJSON(&requestBody); err != nil || requestBody.TermID == 0 {
+		c.String(http.StatusBadRequest, "Нужно передать TermID")
+		return
+	}
+
+	if requestBody.MaxGroupsPerStudent < 0 {
+		c.String(http.StatusBadRequest, "MaxGroupsPerStudent не может быть отрицательным")
+		return
+	}
+
+	_userUUID, _ := c.Get("userUUID")
+	userUUID := _userUUID.(uuid.UUID)
+
+	report, err := a.repo.AllocateTerm(requestBody, userUUID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.String(http.StatusNotFound, "Семестр не найден")
+		return
+	}
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	if !report.DryRun && report.Completed > 0 {
+		a.invalidateGroupsCache(c.Request.Context())
+
+		seats := []ds.GroupSeats{}
+		for _, group := range report.Groups {
+			if group.Assigned > 0 {
+				seats = append(seats, ds.GroupSeats{
+					GroupID:  group.GroupID,
+					Title:    group.Title,
+					Capacity: json.Number(strconv.Itoa(group.Capacity)),
+					Enrolled: json.Number(strconv.Itoa(group.Enrolled + group.Assigned)),
+				})
+			}
+		}
+		a.publishGroupSeats(c.Request.Context(), seats...)
+	}
+
+	if !report.DryRun {
+		for _, result := range report.Enrollments {
+			a.publishEnrollmentStatus(c.Request.Context(), result.EnrollmentID, result.Status, &userUUID)
+		}
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// @Summary      Получить итог распределения
+// @Description  Возвращает сохранённый запуск распределения мест
+// @Tags         Семестры
+// @Produce      json
+// @Success      200  {object}  ds.AllocationRun
+// @Param run_id path int true "id запуска"
+// @Router       /allocation/{run_id} [get]
+func (a *Application) get_allocation_run(c *gin.Context) {
+	run_id, err := strconv.Atoi(c.Param("run_id"))
+	if err != nil {
+		c.String(http.StatusBadRequest, "Не получается прочитать ID запуска")
+		return
+	}
+
+	run, err := a.repo.GetAllocationRun(run_id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.String(http.StatusNotFound, "Запуск распределения не найден")
+		return
+	}
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, run)
+}
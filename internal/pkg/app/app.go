@@ -5,10 +5,12 @@ import (
 	"crypto/sha1"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"log"
 	"net/http"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -23,12 +25,11 @@ import (
 
 	"github.com/golang-jwt/jwt"
 	"github.com/google/uuid"
-	"github.com/minio/minio-go/v7"
-	"github.com/minio/minio-go/v7/pkg/credentials"
 	swaggerfiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 // @BasePath /
@@ -38,6 +39,9 @@ type Application struct {
 	r      *gin.Engine
 	config *config.Config
 	redis  *redis.Client
+	stream *streamHub
+
+	moderation *moderationHub
 }
 
 type loginReq struct {
@@ -73,6 +77,9 @@ func New(ctx context.Context) (*Application, error) {
 		config: cfg,
 		repo:   repo,
 		redis:  redisClient,
+		stream: newStreamHub(),
+
+		moderation: newModerationHub(),
 	}, nil
 }
 
@@ -87,12 +94,35 @@ func (a *Application) StartServer() {
 
 	a.r.Use(a.WithAuthCheck(role.Moderator, role.Admin, role.User, role.Undefined)).GET("groups", a.get_groups)
 	a.r.GET("group/:group", a.get_group)
+	a.r.GET("group/images/:group_id", a.get_group_images)
+	a.r.GET("courses", a.get_courses)
+	a.r.GET("course/:course_id", a.get_course)
+	a.r.GET("locations", a.get_locations)
+	a.r.GET("location/:location_id", a.get_location)
+	a.r.GET("locations/:location_id/occupancy", a.get_location_occupancy)
+	a.r.GET("terms", a.get_terms)
+	a.r.GET("term/current", a.get_current_term)
+	a.r.GET("group/sessions/:group_id", a.get_group_sessions)
 
 	// authorization
 	a.r.POST("/login", a.login)
 	a.r.POST("/register", a.register)
 	a.r.POST("/logout", a.logout)
 
+	coach := a.r.Group("coach", a.WithAuthCheck(role.Coach))
+	coach.GET("me", a.get_coach_me)
+	coach.PUT("me", a.edit_coach_me)
+	coach.GET("groups", a.get_coach_groups)
+	coach.GET("roster/:group_id", a.get_coach_roster)
+	coach.GET("waitlist/:group_id", a.get_coach_waitlist)
+	coach.PUT("group/description", a.edit_coach_group_description)
+	coach.GET("session/attendance/:session_id", a.get_session_attendance)
+	coach.PUT("attendance/mark", a.mark_attendance)
+	coach.GET("session/checkin/:session_id", a.get_checkin_code)
+	coach.PUT("session/cancel", a.cancel_sessions)
+	coach.PUT("session/move", a.move_session)
+	coach.GET("group/attendance/:group_id", a.get_group_attendance_stats)
+
 	a.r.Use(a.WithAuthCheck(role.Moderator, role.Admin, role.User)).GET("enrollment", a.get_enrollment)
 	a.r.POST("group/add_to_enrollment/:id", a.add_group_to_enrollment)
 	a.r.DELETE("enrollment_to_group/delete", a.delete_enrollment_to_group)
@@ -105,12 +135,81 @@ func (a *Application) StartServer() {
 	a.r.PUT("enrollment_to_group/set_group_availability", a.enrollment_to_group_set_group_availability)
 	a.r.GET("enrollment_groups/:enrollment_id", a.enrollment_groups)
 	a.r.PUT("enrollment/set_groups", a.set_enrollment_groups)
+	a.r.PUT("enrollment/preferences", a.set_enrollment_preferences)
+	a.r.GET("enrollment/comments/:enrollment_id", a.get_enrollment_comments)
+	a.r.POST("enrollment/comments/:enrollment_id", a.add_enrollment_comment)
+	a.r.GET("me", a.get_me)
+	a.r.PUT("me", a.edit_me)
+	a.r.GET("me/credits", a.get_my_credits)
+	a.r.GET("me/schedule", a.get_my_schedule)
+	a.r.GET("notifications", a.get_notifications)
+	a.r.PUT("notification/read/:notification_id", a.mark_notification_read)
+	a.r.PUT("notifications/read_all", a.mark_all_notifications_read)
+	a.r.GET("notifications/preferences", a.get_notification_preferences)
+	a.r.PUT("notifications/preferences", a.edit_notification_preferences)
+	a.r.GET("events", a.get_events)
+	a.r.POST("medical_certificate/upload", a.upload_medical_certificate)
+	a.r.GET("medical_certificates/my", a.get_my_medical_certificates)
+	a.r.GET("medical_certificate/file/:certificate_id", a.get_medical_certificate_file)
+	a.r.GET("attendance/my", a.get_my_attendance)
+	a.r.POST("attendance/checkin", a.checkin)
 
 	a.r.Use(a.WithAuthCheck(role.Moderator, role.Admin)).POST("group/add_image/:group_id", a.add_image)
+	a.r.POST("group/add_gallery_image/:group_id", a.add_group_image)
+	a.r.PUT("group/image/edit", a.edit_group_image)
+	a.r.PUT("group/images/reorder", a.reorder_group_images)
+	a.r.PUT("group/image/set_cover/:image_id", a.set_group_cover_image)
+	a.r.DELETE("group/image/delete/:image_id", a.delete_group_image)
 	a.r.PUT("enrollment/moderator_confirm/:enrollment_id", a.moderator_confirm_enrollment)
 	a.r.DELETE("group/delete/:group_title", a.delete_group)
 	a.r.PUT("group/edit", a.edit_group)
 	a.r.POST("group/add", a.add_group)
+	a.r.GET("coaches", a.get_coaches)
+	a.r.POST("coach/add", a.add_coach)
+	a.r.PUT("coach/edit", a.edit_coach)
+	a.r.PUT("coach/link", a.link_coach_user)
+	a.r.GET("medical_certificates", a.get_medical_certificates)
+	a.r.PUT("medical_certificate/review", a.review_medical_certificate)
+	a.r.GET("moderation/queue", a.moderation_queue)
+	a.r.PUT("enrollment/claim/:enrollment_id", a.claim_enrollment)
+	a.r.DELETE("enrollment/claim/:enrollment_id", a.release_enrollment_claim)
+	a.r.POST("enrollments/moderate", a.bulk_moderate_enrollments)
+	a.r.GET("enrollments/moderate/:job_id", a.get_bulk_moderation_job)
+	a.r.GET("rejection_reasons", a.get_rejection_reasons)
+	a.r.POST("group/sessions/generate", a.generate_group_sessions)
+	a.r.GET("session/attendance/:session_id", a.get_session_attendance)
+	a.r.PUT("attendance/mark", a.mark_attendance)
+	a.r.GET("session/checkin/:session_id", a.get_checkin_code)
+	a.r.PUT("session/cancel", a.cancel_sessions)
+	a.r.PUT("session/move", a.move_session)
+	a.r.GET("group/attendance/:group_id", a.get_group_attendance_stats)
+	a.r.GET("credits/report", a.get_credits_report)
+
+	a.r.Use(a.WithAuthCheck(role.Admin)).POST("course/add", a.add_course)
+	a.r.PUT("course/edit", a.edit_course)
+	a.r.DELETE("course/delete/:course_id", a.delete_course)
+	a.r.POST("course/add_image/:course_id", a.add_course_image)
+	a.r.POST("location/add", a.add_location)
+	a.r.PUT("location/edit", a.edit_location)
+	a.r.DELETE("location/delete/:location_id", a.delete_location)
+	a.r.POST("term/add", a.add_term)
+	a.r.PUT("term/edit", a.edit_term)
+	a.r.POST("term/rollover", a.rollover_groups)
+	a.r.POST("term/allocate", a.allocate_term)
+	a.r.GET("allocation/:run_id", a.get_allocation_run)
+	a.r.GET("rules", a.get_rules)
+	a.r.POST("rule/add", a.add_rule)
+	a.r.PUT("rule/edit", a.edit_rule)
+	a.r.DELETE("rule/delete/:rule_id", a.delete_rule)
+	a.r.GET("credit_requirements", a.get_credit_requirements)
+	a.r.POST("credit_requirement/add", a.add_credit_requirement)
+	a.r.PUT("credit_requirement/edit", a.edit_credit_requirement)
+	a.r.DELETE("credit_requirement/delete/:requirement_id", a.delete_credit_requirement)
+
+	a.failUnfinishedBulkModeration()
+	a.startEventStream(context.Background())
+	a.startModerationQueue(context.Background())
+	a.startRevocationSync(context.Background())
 
 	a.r.Run()
 
@@ -118,32 +217,98 @@ func (a *Application) StartServer() {
 }
 
 // @Summary Получить все существующие группы
-// @Description Возвращает все существующие группы
+// @Description Возвращает группы, подходящие под фильтры, и количество групп по каждому значению фильтров
 // @Tags Группы
 // @Accept json
 // @Produce json
 // @Success 200 {} json
-// @Param name_pattern query string false "Паттерн названия группы"
-// @Param location query string false "Локация"
+// @Param q query string false "Полнотекстовый поиск по названию, курсу, описанию и тренеру"
+// @Param title_pattern query string false "Паттерн названия группы"
+// @Param course query string false "Название курса"
+// @Param course_id query int false "id курса"
+// @Param location query string false "Место (корпус, помещение)"
+// @Param location_id query int false "id места"
+// @Param term query string false "id семестра или all (по умолчанию семестр, на который идёт запись, иначе текущий)"
+// @Param weekday query string false "День недели (пн/вт/ср/чт/пт/сб/вс)"
+// @Param has_free_seats query bool false "Есть ли свободные места"
 // @Param status query string false "Статус группы (Действует/Недействителен)"
+// @Param limit query int false "Размер страницы"
+// @Param offset query int false "Смещение от начала списка"
+// @Param cursor query string false "Курсор следующей страницы из next_cursor"
+// @Param sort query string false "Сортировка, например -title,id"
 // @Router /groups [get]
 func (a *Application) get_groups(c *gin.Context) {
-	var title_pattern = c.Query("title_pattern")
-	var course = c.Query("course")
-	var status = c.Query("status")
+	filter := ds.GroupFilter{
+		TitlePattern: c.Query("title_pattern"),
+		Query:        strings.TrimSpace(c.Query("q")),
+		Course:       c.Query("course"),
+		Location:     c.Query("location"),
+		Weekday:      strings.ToLower(c.Query("weekday")),
+		Status:       c.Query("status"),
+	}
+
+	if course_id := c.Query("course_id"); course_id != "" {
+		value, err := strconv.ParseUint(course_id, 10, 64)
+		if err != nil {
+			c.String(http.StatusBadRequest, "Передан некорректный id курса")
+			return
+		}
+		filter.CourseID = uint(value)
+	}
+
+	if location_id := c.Query("location_id"); location_id != "" {
+		value, err := strconv.ParseUint(location_id, 10, 64)
+		if err != nil {
+			c.String(http.StatusBadRequest, "Передан некорректный id места")
+			return
+		}
+		filter.LocationID = uint(value)
+	}
+
+	term_id, ok := a.parseTermFilter(c, a.repo.GetCatalogueTerm)
+	if !ok {
+		return
+	}
+	filter.TermID = term_id
+
+	if filter.Weekday != "" && !slices.Contains(repository.Weekdays, filter.Weekday) {
+		c.String(http.StatusBadRequest, "Передан некорректный день недели")
+		return
+	}
+
+	if has_free_seats := c.Query("has_free_seats"); has_free_seats != "" {
+		value, err := strconv.ParseBool(has_free_seats)
+		if err != nil {
+			c.String(http.StatusBadRequest, "Передан некорректный флаг свободных мест")
+			return
+		}
+		filter.HasFreeSeats = &value
+	}
+
+	page, err := parsePageRequest(c, repository.GroupSortColumns)
+	if err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	result, err := a.getGroupsCached(c.Request.Context(), filter, page)
+	if isPageError(err) {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
 
-	groups, err := a.repo.GetGroups(title_pattern, course, status)
 	if err != nil {
 		c.Error(err)
 		return
 	}
 
+	response := pageEnvelope(c, result.Groups, page, result.Info)
+	response["facets"] = result.Facets
+
 	_userUUID, ok := c.Get("userUUID")
 
 	if !ok {
-		c.JSON(http.StatusOK, gin.H{
-			"groups": groups,
-		})
+		writeJSONWithETag(c, response)
 		return
 	}
 
@@ -157,10 +322,9 @@ func (a *Application) get_groups(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"groups":           groups,
-		"draft_enrollment": draft_enrollment,
-	})
+	response["draft_enrollment"] = draft_enrollment
+
+	writeJSONWithETag(c, response)
 }
 
 // @Summary      Добавляет новую группу в БД
@@ -183,13 +347,29 @@ func (a *Application) add_group(c *gin.Context) {
 		group.Status = "Черновик"
 	}
 
+	if group.Capacity == "" && group.CourseRefer != nil {
+		course, err := a.repo.GetCourseByID(int(*group.CourseRefer))
+		if err != nil {
+			c.String(http.StatusBadRequest, "Курс группы не найден")
+			return
+		}
+		group.Capacity = json.Number(strconv.Itoa(course.DefaultCapacity))
+	}
+
+	if err := validateGroupSessions(group.Sessions); err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
 	err := a.repo.CreateGroup(group)
 
 	if err != nil {
-		c.String(http.StatusNotFound, "Не получается создать группу\n"+err.Error())
+		writeGroupSaveError(c, err, http.StatusNotFound, "Не получается создать группу")
 		return
 	}
 
+	a.invalidateGroupsCache(c.Request.Context())
+
 	c.String(http.StatusCreated, "Группа успешно добавлена")
 
 }
@@ -201,18 +381,14 @@ func (a *Application) add_group(c *gin.Context) {
 // @Success      200  {object}  string
 // @Router       /group/{group} [get]
 func (a *Application) get_group(c *gin.Context) {
-	var group = ds.Group{}
-
-	group.Title = c.Param("group")
-
-	found_group, err := a.repo.FindGroup(group)
+	found_group, err := a.findGroupCached(c.Request.Context(), c.Param("group"))
 
 	if err != nil {
 		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, found_group)
+	writeJSONWithETag(c, found_group)
 
 }
 
@@ -232,13 +408,36 @@ func (a *Application) edit_group(c *gin.Context) {
 		return
 	}
 
+	if err := validateGroupSessions(group.Sessions); err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
 	err := a.repo.EditGroup(group)
 
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.String(http.StatusNotFound, "Группа не найдена")
+		return
+	}
+
 	if err != nil {
-		c.Error(err)
+		writeGroupSaveError(c, err, http.StatusInternalServerError, "Не получается изменить группу")
 		return
 	}
 
+	a.invalidateGroupsCache(c.Request.Context())
+
+	if group.Capacity != "" || group.Enrolled != "" {
+		if edited, err := a.repo.FindGroup(ds.Group{ID: group.ID, Title: group.Title}); err == nil && edited.ID != 0 {
+			a.publishGroupSeats(c.Request.Context(), ds.GroupSeats{
+				GroupID:  edited.ID,
+				Title:    edited.Title,
+				Capacity: edited.Capacity,
+				Enrolled: edited.Enrolled,
+			})
+		}
+	}
+
 	c.String(http.StatusCreated, "Группа была успешно изменена")
 }
 
@@ -266,11 +465,14 @@ func (a *Application) delete_group(c *gin.Context) {
 		return
 	}
 
+	// удаление логическое, поэтому галерея остаётся на случай восстановления группы
+	a.invalidateGroupsCache(c.Request.Context())
+
 	c.String(http.StatusFound, "Группа был успешно удалена")
 }
 
 // @Summary      Записать в группу/ы
-// @Description  Создаёт новую заявку и связывает её с группой/ами
+// @Description  Создаёт новую черновую заявку и связывает её с группой/ами. Группы проверяются по правилам записи
 // @Tags Запись
 // @Accept json
 // @Produce      json
@@ -293,7 +495,40 @@ func (a *Application) enroll(c *gin.Context) {
 	}
 
 	userUUID := _userUUID.(uuid.UUID)
-	err := a.repo.Enroll(request_body, userUUID)
+
+	group_ids := make([]int, 0, len(request_body.Groups))
+	for _, groupTitle := range request_body.Groups {
+		group_id, err := a.repo.GetGroupID(groupTitle)
+		if err != nil {
+			c.String(http.StatusNotFound, "Не могу найти группу "+groupTitle)
+			return
+		}
+
+		if slices.Contains(group_ids, group_id) {
+			continue
+		}
+		group_ids = append(group_ids, group_id)
+
+		term, err := a.repo.GetGroupTerm(group_id)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		if !enrollmentWindowOpen(c, term) {
+			return
+		}
+	}
+
+	if !a.checkNewEnrollmentRules(c, userUUID, group_ids) {
+		return
+	}
+
+	err := a.repo.Enroll(group_ids, userUUID)
+	if errors.Is(err, repository.ErrTermMismatch) {
+		c.String(http.StatusConflict, err.Error())
+		return
+	}
 
 	if err != nil {
 		c.Error(err)
@@ -310,6 +545,16 @@ func (a *Application) enroll(c *gin.Context) {
 // @Produce      json
 // @Success      302  {object}  string
 // @Param status query string false "Статус записи"
+// @Param term query string false "id семестра или all (по умолчанию семестр, на который идёт запись, иначе текущий)"
+// @Param study_group query string false "Учебная группа студента или её начало, например ИУ5"
+// @Param faculty query string false "Факультет студента"
+// @Param year query int false "Курс обучения студента"
+// @Param medical_group query string false "Медицинская группа студента"
+// @Param limit query int false "Размер страницы"
+// @Param offset query int false "Смещение от начала списка"
+// @Param cursor query string false "Курсор следующей страницы из next_cursor"
+// @Param sort query string false "Сортировка, например -date_created,id"
+// @Param expand query string false "Связанные данные: groups,user,moderator (по умолчанию user,moderator)"
 // @Router       /enrollments [get]
 func (a *Application) get_enrollments(c *gin.Context) {
 	_roleNumber, _ := c.Get("role")
@@ -318,25 +563,63 @@ func (a *Application) get_enrollments(c *gin.Context) {
 	roleNumber := _roleNumber.(role.Role)
 	userUUID := _userUUID.(uuid.UUID)
 
-	status := c.Query("status")
-	startDate := c.Query("startDate")
-	endDate := c.Query("endDate")
+	filter := ds.EnrollmentFilter{
+		Status:       c.Query("status"),
+		StartDate:    c.Query("startDate"),
+		EndDate:      c.Query("endDate"),
+		StudyGroup:   strings.TrimSpace(c.Query("study_group")),
+		Faculty:      strings.TrimSpace(c.Query("faculty")),
+		MedicalGroup: c.Query("medical_group"),
+	}
+
+	if year := c.Query("year"); year != "" {
+		value, err := strconv.Atoi(year)
+		if err != nil {
+			c.String(http.StatusBadRequest, "Передан некорректный курс обучения")
+			return
+		}
+		filter.Year = value
+	}
+
+	term_id, ok := a.parseTermFilter(c, a.repo.GetCatalogueTerm)
+	if !ok {
+		return
+	}
+	filter.TermID = term_id
+
+	page, err := parsePageRequest(c, repository.EnrollmentSortColumns)
+	if err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	expand, err := parseEnrollmentExpand(c)
+	if err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	enrollments, info, err := a.repo.GetEnrollments(filter, roleNumber, userUUID, page, expand)
+	if isPageError(err) {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
 
-	enrollments, err := a.repo.GetEnrollments(status, startDate, endDate, roleNumber, userUUID)
 	if err != nil {
 		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, enrollments)
+	c.JSON(http.StatusOK, pageEnvelope(c, enrollments, page, info))
 }
 
-// @Summary      Получить запись
+// @Description  Возвращает запись с переданными параметрами. Студент видит только свои записи
 // @Description  Возвращает запись с переданными параметрами
 // @Tags         Записи
 // @Accept		 json
 // @Produce      json
 // @Success      302  {object}  string
+// @Param expand query string false "Связанные данные: groups,user,moderator (по умолчанию user,moderator)"
 // @Router       /enrollment [get]
 func (a *Application) get_enrollment(c *gin.Context) {
 	status := c.Query("status")
@@ -347,7 +630,35 @@ func (a *Application) get_enrollment(c *gin.Context) {
 		ID:     uint(id),
 	}
 
-	found_enrollment, err := a.repo.FindEnrollment(enrollment)
+	expand, err := parseEnrollmentExpand(c)
+	if err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// студент видит только свои записи: профиль загружается после того, как
+	// проверено, что запись его
+	_userUUID, _ := c.Get("userUUID")
+	_userRole, _ := c.Get("role")
+	if userRole, _ := _userRole.(role.Role); userRole == role.User {
+		userUUID := _userUUID.(uuid.UUID)
+		if enrollment.ID == 0 {
+			enrollment.UserRefer = &userUUID
+		} else {
+			owned, err := a.repo.FindEnrollment(enrollment, ds.EnrollmentExpand{})
+			if err != nil {
+				c.Error(err)
+				return
+			}
+
+			if owned.ID != 0 && (owned.UserRefer == nil || *owned.UserRefer != userUUID) {
+				c.String(http.StatusForbidden, "Это не ваша запись")
+				return
+			}
+		}
+	}
+
+	found_enrollment, err := a.repo.FindEnrollment(enrollment, expand)
 
 	if err != nil {
 		c.Error(err)
@@ -414,16 +725,36 @@ func (a *Application) set_enrollment_groups(c *gin.Context) {
 		return
 	}
 
+	group_ids := make([]int, 0, len(requestBody.Groups))
+	for _, groupTitle := range requestBody.Groups {
+		group_id, err := a.repo.GetGroupID(groupTitle)
+		if err != nil || group_id == 0 {
+			c.String(http.StatusNotFound, "Не могу найти группу "+groupTitle)
+			return
+		}
+		group_ids = append(group_ids, group_id)
+	}
+
+	if !a.checkEnrollmentRules(c, requestBody.EnrollmentID, group_ids) {
+		return
+	}
+
 	err := a.repo.SetEnrollmentGroups(requestBody.EnrollmentID, requestBody.Groups)
+	if errors.Is(err, repository.ErrTermMismatch) {
+		c.String(http.StatusConflict, err.Error())
+		return
+	}
+
 	if err != nil {
 		c.String(http.StatusInternalServerError, "Не получилось задать группы для записи\n"+err.Error())
+		return
 	}
 
 	c.String(http.StatusCreated, "Группы записи успешно заданы!")
 }
 
 // @Summary      Редактировать статус записи
-// @Description  Получает id заявки и новый статус и производит необходимые обновления
+// @Description  Получает id заявки и новый статус и производит необходимые обновления. Студент может только удалить или сформировать свою запись. Модератор одобряет или отклоняет только взятую в работу запись, для отклонения нужны ReasonCode и Comment
 // @Tags         Запись
 // @Accept json
 // @Produce json
@@ -445,50 +776,110 @@ func (a *Application) enrollment_status_change(c *gin.Context) {
 	userRole := _userRole.(role.Role)
 
 	status, err := a.repo.GetEnrollmentStatus(requestBody.EnrollmentID)
-	if err == nil {
+	if err != nil {
 		c.Error(err)
 		return
 	}
 
-	if userRole == role.User && requestBody.Status == "Удалён" {
-		if status == "Черновик" || status == "Сформирован" {
-			err = a.repo.ChangeEnrollmentStatusUser(requestBody.EnrollmentID, requestBody.Status, userUUID)
+	if status == "" {
+		c.String(http.StatusNotFound, "Запись не найдена")
+		return
+	}
 
-			if err != nil {
-				c.Error(err)
-				return
-			} else {
-				c.String(http.StatusCreated, "Статус записи был успешно обновлён")
-			}
+	if userRole == role.User {
+		a.userEnrollmentStatusChange(c, requestBody, status, userUUID)
+		return
+	}
+
+	// решение по записи модератор принимает только по взятой в работу записи
+	// и с причиной отклонения
+	if requestBody.Status == "Завершён" || requestBody.Status == "Отклонён" {
+		if a.decideEnrollment(c, requestBody.EnrollmentID, requestBody.Status == "Завершён", requestBody.RejectionRequestBody) {
+			c.String(http.StatusCreated, "Статус записи был успешно обновлён")
 		}
-	} else {
-		err = a.repo.ChangeEnrollmentStatus(requestBody.EnrollmentID, requestBody.Status)
+		return
+	}
+
+	if requestBody.Status == "Сформирован" && !a.checkFormingEnrollment(c, requestBody.EnrollmentID) {
+		return
+	}
+
+	err = a.repo.ChangeEnrollmentStatus(requestBody.EnrollmentID, requestBody.Status)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	if userRole == role.Moderator && status == "Черновик" {
+		err = a.repo.SetEnrollmentModerator(requestBody.EnrollmentID, userUUID)
 
 		if err != nil {
 			c.Error(err)
 			return
 		}
+	}
 
-		if userRole == role.Moderator && status == "Черновик" {
-			err = a.repo.SetEnrollmentModerator(requestBody.EnrollmentID, userUUID)
+	a.publishEnrollmentStatus(c.Request.Context(), uint(requestBody.EnrollmentID), requestBody.Status, &userUUID)
 
-			if err != nil {
-				c.Error(err)
-				return
-			}
+	c.String(http.StatusCreated, "Статус записи был успешно обновлён")
+}
+
+// userEnrollmentStatusChange - смена статуса студентом: он может только
+// удалить свою черновую или сформированную запись либо сформировать черновик.
+func (a *Application) userEnrollmentStatusChange(c *gin.Context, requestBody ds.ChangeEnrollmentStatusRequestBody, status string, userUUID uuid.UUID) {
+	enrollment, err := a.repo.FindEnrollment(&ds.Enrollment{ID: uint(requestBody.EnrollmentID)}, ds.EnrollmentExpand{})
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	if enrollment.UserRefer == nil || *enrollment.UserRefer != userUUID {
+		c.String(http.StatusForbidden, "Это не ваша запись")
+		return
+	}
+
+	switch requestBody.Status {
+	case "Удалён":
+		if status != "Черновик" && status != "Сформирован" {
+			c.String(http.StatusConflict, "Удалить можно только черновик или сформированную запись")
+			return
+		}
+
+		err = a.repo.ChangeEnrollmentStatusUser(requestBody.EnrollmentID, requestBody.Status, userUUID)
+	case "Сформирован":
+		if status != "Черновик" {
+			c.String(http.StatusConflict, "Сформировать можно только черновик")
+			return
 		}
 
-		c.String(http.StatusCreated, "Статус записи был успешно обновлён")
+		var term *ds.Term
+		term, err = a.repo.GetEnrollmentTerm(requestBody.EnrollmentID)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		if !enrollmentWindowOpen(c, term) || !a.checkFormingEnrollment(c, requestBody.EnrollmentID) {
+			return
+		}
+
+		err = a.repo.UserConfirmEnrollment(userUUID, requestBody.EnrollmentID)
+	default:
+		c.String(http.StatusForbidden, "Студент может только удалить или сформировать запись")
+		return
 	}
-}
 
-type changeEnrollmentToGroupAvailabilityReq struct {
-	enrollmentToGroupId int
-	availability        string
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	a.publishEnrollmentStatus(c.Request.Context(), uint(requestBody.EnrollmentID), requestBody.Status, nil)
+	c.String(http.StatusCreated, "Статус записи был успешно обновлён")
 }
 
 // @Summary      Редактировать статус м-м
-// @Description  Получает id записи м-м и новый статус и производит необходимые обновления
+// @Description  Получает id записи м-м и новый статус и производит необходимые обновления. Доступно модераторам; если студента зачисляют в группу, где ему не хватило мест, он получает уведомление
 // @Tags         Запись
 // @Accept json
 // @Produce json
@@ -496,21 +887,29 @@ type changeEnrollmentToGroupAvailabilityReq struct {
 // @Param request_body body ds.ChangeEnrollmentToGroupAvailabilityRequestBody true "Request body"
 // @Router /enrollment/set_group_availability [put]
 func (a *Application) enrollment_to_group_set_group_availability(c *gin.Context) {
-	req := &changeEnrollmentToGroupAvailabilityReq{}
-	err := json.NewDecoder(c.Request.Body).Decode(req)
-	if err != nil {
-		c.AbortWithError(http.StatusBadRequest, err)
+	var requestBody ds.ChangeEnrollmentToGroupAvailabilityRequestBody
+
+	if err := c.BindJSON(&requestBody); err != nil {
+		c.String(http.StatusBadRequest, "Не получается распознать json запрос")
+		return
+	}
+
+	_userRole, _ := c.Get("role")
+	if userRole, _ := _userRole.(role.Role); userRole != role.Moderator && userRole != role.Admin {
+		c.String(http.StatusForbidden, "Доступность группы меняет модератор")
 		return
 	}
 
 	enrollment_to_group := &ds.EnrollmentToGroup{}
-	enrollment_to_group.ID = uint(req.enrollmentToGroupId)
-	enrollment_to_group.Availability = req.availability
+	enrollment_to_group.ID = uint(requestBody.EnrollmentID)
+	enrollment_to_group.Availability = requestBody.Availability
 
-	// _userUUID, _ := c.Get("userUUID")
-	// userUUID := _userUUID.(uuid.UUID)
+	err := a.repo.ChangeEnrollmentToGroupAvailability(enrollment_to_group)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.String(http.StatusNotFound, "Группа в записи не найдена")
+		return
+	}
 
-	err = a.repo.ChangeEnrollmentToGroupAvailability(enrollment_to_group)
 	if err != nil {
 		c.Error(err)
 		return
@@ -537,6 +936,8 @@ func (a *Application) delete_enrollment(c *gin.Context) {
 		return
 	}
 
+	a.publishEnrollmentStatus(c.Request.Context(), uint(enrollment_id), "Удалён", nil)
+
 	c.String(http.StatusFound, "Запись была успешно удалена")
 }
 
@@ -686,7 +1087,7 @@ func (a *Application) register(c *gin.Context) {
 }
 
 // @Summary Выйти из системы
-// @Details Деактивирует текущий токен пользователя, добавляя его в блэклист в редисе
+// @Details Деактивирует текущий токен пользователя: отзыв сохраняется в базе и добавляется в блэклист в редисе
 // @Tags Аутентификация
 // @Produce json
 // @Accept json
@@ -712,7 +1113,7 @@ func (a *Application) logout(c *gin.Context) {
 		return
 	}
 
-	err = a.redis.WriteJWTToBlackList(c.Request.Context(), jwtStr, 3600000000000)
+	err = a.revokeToken(c.Request.Context(), jwtStr)
 	if err != nil {
 		c.AbortWithError(http.StatusInternalServerError, err)
 
@@ -741,16 +1142,61 @@ func (a *Application) moderator_confirm_enrollment(c *gin.Context) {
 		return
 	}
 
+	var rejection ds.RejectionRequestBody
+	if !confirm {
+		if err := c.ShouldBindJSON(&rejection); err != nil {
+			c.String(http.StatusBadRequest, "Не получается распознать причину отклонения")
+			return
+		}
+
+		if err := repository.CheckRejection(rejection); err != nil {
+			c.String(http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	if !a.decideEnrollment(c, enrollment_id, confirm, rejection) {
+		return
+	}
+
+	c.String(http.StatusOK, "Статус обновлён!")
+}
+
+// decideEnrollment одобряет или отклоняет сформированную запись от имени
+// модератора, который держит её в работе, и сообщает об этом очереди.
+func (a *Application) decideEnrollment(c *gin.Context, enrollment_id int, confirm bool, rejection ds.RejectionRequestBody) bool {
 	_userUUID, _ := c.Get("userUUID")
 	userUUID := _userUUID.(uuid.UUID)
 
-	err = a.repo.ModeratorConfirmEnrollment(userUUID, enrollment_id, confirm)
+	if !a.holdsClaim(c, uint(enrollment_id)) {
+		return false
+	}
+
+	status, err := a.repo.ModeratorConfirmEnrollment(userUUID, uint(enrollment_id), confirm, rejection)
+	if errors.Is(err, repository.ErrInvalidRejection) {
+		c.String(http.StatusBadRequest, err.Error())
+		return false
+	}
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.String(http.StatusNotFound, "Запись не найдена")
+		return false
+	}
+
+	if errors.Is(err, repository.ErrEnrollmentProcessed) {
+		c.String(http.StatusConflict, err.Error())
+		return false
+	}
+
 	if err != nil {
 		c.String(http.StatusInternalServerError, "Не получается обновить статус!")
-		return
+		return false
 	}
 
-	c.String(http.StatusOK, "Статус обновлён!")
+	a.releaseProcessedClaim(c.Request.Context(), uint(enrollment_id))
+	a.publishEnrollmentStatus(c.Request.Context(), uint(enrollment_id), status, &userUUID)
+
+	return true
 }
 
 func (a *Application) user_confirm_enrollment(c *gin.Context) {
@@ -765,12 +1211,38 @@ func (a *Application) user_confirm_enrollment(c *gin.Context) {
 	_userUUID, _ := c.Get("userUUID")
 	userUUID := _userUUID.(uuid.UUID)
 
+	term, err := a.repo.GetEnrollmentTerm(enrollment_id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	if !enrollmentWindowOpen(c, term) {
+		return
+	}
+
+	group_ids, err := a.enrollmentGroupIDs(enrollment_id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	if !a.checkEnrollmentRules(c, enrollment_id, group_ids) {
+		return
+	}
+
+	if !a.checkMedicalClearance(c, enrollment_id, userUUID) {
+		return
+	}
+
 	err = a.repo.UserConfirmEnrollment(userUUID, enrollment_id)
 	if err != nil {
 		c.String(http.StatusInternalServerError, "Не получается обновить статус!")
 		return
 	}
 
+	a.publishEnrollmentStatus(c.Request.Context(), uint(enrollment_id), "Сформирован", nil)
+
 	c.String(http.StatusOK, "Статус обновлён!")
 }
 
@@ -791,6 +1263,16 @@ func (a *Application) add_group_to_enrollment(c *gin.Context) {
 	}
 	userUUID := _userUUID.(uuid.UUID)
 
+	term, err := a.repo.GetGroupTerm(group_id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	if !enrollmentWindowOpen(c, term) {
+		return
+	}
+
 	draft, err := a.repo.GetDraftEnrollment(userUUID)
 	if err != nil {
 		c.String(http.StatusInternalServerError, "Не могу найти черновую запись!")
@@ -815,6 +1297,29 @@ func (a *Application) add_group_to_enrollment(c *gin.Context) {
 		}
 	}
 
+	group_ids, err := a.enrollmentGroupIDs(int(draft.ID))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	if term != nil {
+		err = a.repo.SetEnrollmentTerm(int(draft.ID), term.ID)
+		if errors.Is(err, repository.ErrTermMismatch) {
+			c.String(http.StatusConflict, err.Error())
+			return
+		}
+
+		if err != nil {
+			c.String(http.StatusInternalServerError, "Не могу привязать запись к семестру!")
+			return
+		}
+	}
+
+	if !slices.Contains(group_ids, group_id) && !a.checkEnrollmentRules(c, int(draft.ID), append(group_ids, group_id)) {
+		return
+	}
+
 	group_to_draft := ds.EnrollmentToGroup{}
 	group_to_draft.EnrollmentRefer = int(draft.ID)
 	group_to_draft.GroupRefer = group_id
@@ -835,43 +1340,28 @@ func (a *Application) add_image(c *gin.Context) {
 		return
 	}
 
-	image, header, err := c.Request.FormFile("file")
-
-	if err != nil {
-		c.String(http.StatusBadRequest, "Не получается распознать картинку")
-		log.Println("Не получается распознать картинку")
-		return
-	}
-	defer image.Close()
-
-	minioClient, err := minio.New("127.0.0.1:9000", &minio.Options{
-		Creds:  credentials.NewStaticV4("minioadmin", "minioadmin", ""),
-		Secure: false,
-	})
-
-	if err != nil {
-		c.String(http.StatusInternalServerError, "Не получается подключиться к minio")
-		log.Println("Не получается подключиться к minio")
-		return
-	}
-
-	objectName := header.Filename
-	_, err = minioClient.PutObject(c.Request.Context(), "groupimages", objectName, image, header.Size, minio.PutObjectOptions{})
-
+	objectName, err := uploadFormFile(c, groupImagesBucket)
 	if err != nil {
 		c.String(http.StatusInternalServerError, "Не получилось загрузить картинку в minio")
-		log.Println("Не получилось загрузить картинку в minio")
+		log.Println("Не получилось загрузить картинку в minio:", err)
 		return
 	}
 
-	err = a.repo.SetGroupImage(group_id, objectName)
+	err = a.repo.AddGroupImage(&ds.GroupImage{
+		GroupRefer: group_id,
+		ImageName:  objectName,
+		IsCover:    true,
+	})
 
 	if err != nil {
+		removeObjects(c.Request.Context(), groupImagesBucket, objectName)
 		c.String(http.StatusInternalServerError, "Не получается обновить картинку группы")
 		log.Println("Не получается обновить картинку группы")
 		return
 	}
 
+	a.invalidateGroupsCache(c.Request.Context())
+
 	c.String(http.StatusCreated, "Картинка загружена!")
 }
 
@@ -0,0 +1,269 @@
+package app
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"sports_courses/internal/app/ds"
+	"sports_courses/internal/app/repository"
+	"sports_courses/internal/app/role"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// @Summary      Занятия группы
+// @Description  Возвращает занятия группы за период (даты в формате ГГГГ-ММ-ДД)
+// @Tags         Посещаемость
+// @Produce      json
+// @Success      200  {array}  ds.SessionInstance
+// @Param group_id path int true "id группы"
+// @Param from query string false "Начало периода"
+// @Param to query string false "Конец периода"
+// @Router       /group/sessions/{group_id} [get]
+func (a *Application) get_group_sessions(c *gin.Context) {
+	group_id, err := strconv.Atoi(c.Param("group_id"))
+	if err != nil {
+		c.String(http.StatusBadRequest, "Не получается прочитать ID группы")
+		return
+	}
+
+	from, to, err := parsePeriod(c)
+	if err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	instances, err := a.repo.GetGroupSessionInstances(group_id, from, to)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, instances)
+}
+
+// @Summary      Создать занятия группы
+// @Description  Создаёт занятия группы по её расписанию на период, по умолчанию на весь семестр группы. Уже созданные занятия не дублируются
+// @Tags         Посещаемость
+// @Accept       json
+// @Produce      json
+// @Success      200  {array}  ds.SessionInstance
+// @Param request_body body ds.GenerateSessionsRequestBody true "Группа и период"
+// @Router       /group/sessions/generate [post]
+func (a *Application) generate_group_sessions(c *gin.Context) {
+	var requestBody ds.GenerateSessionsRequestBody
+
+	if err := c.BindJSON(&requestBody); err != nil || requestBody.GroupID == 0 {
+		c.String(http.StatusBadRequest, "Не получается распознать json запрос")
+		return
+	}
+
+	instances, err := a.repo.GenerateGroupSessions(requestBody.GroupID, requestBody.From, requestBody.To)
+	if errors.Is(err, repository.ErrNoSessionPeriod) || errors.Is(err, repository.ErrInvalidPeriod) {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.String(http.StatusNotFound, "Группа не найдена")
+		return
+	}
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, instances)
+}
+
+// @Summary      Отметки на занятии
+// @Description  Возвращает отметки посещаемости на занятии. Тренер видит только занятия своих групп
+// @Tags         Посещаемость
+// @Produce      json
+// @Success      200  {array}  ds.Attendance
+// @Param session_id path int true "id занятия"
+// @Router       /session/attendance/{session_id} [get]
+func (a *Application) get_session_attendance(c *gin.Context) {
+	session_id, err := strconv.Atoi(c.Param("session_id"))
+	if err != nil {
+		c.String(http.StatusBadRequest, "Не получается прочитать ID занятия")
+		return
+	}
+
+	if _, ok := a.attendanceSession(c, session_id); !ok {
+		return
+	}
+
+	marks, err := a.repo.GetSessionAttendance(session_id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, marks)
+}
+
+// @Summary      Отметить посещаемость
+// @Description  Сохраняет отметки студентов на занятии, заменяя прежние. Тренер отмечает только занятия своих групп
+// @Tags         Посещаемость
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  string
+// @Param request_body body ds.MarkAttendanceRequestBody true "Отметки"
+// @Router       /attendance/mark [put]
+func (a *Application) mark_attendance(c *gin.Context) {
+	var requestBody ds.MarkAttendanceRequestBody
+
+	if err := c.BindJSON(&requestBody); err != nil || requestBody.SessionID == 0 {
+		c.String(http.StatusBadRequest, "Не получается распознать json запрос")
+		return
+	}
+
+	for _, mark := range requestBody.Marks {
+		if !slices.Contains(ds.AttendanceStatuses, mark.Status) {
+			c.String(http.StatusBadRequest, "Неизвестная отметка \""+mark.Status+"\"")
+			return
+		}
+	}
+
+	if _, ok := a.attendanceSession(c, int(requestBody.SessionID)); !ok {
+		return
+	}
+
+	_userUUID, _ := c.Get("userUUID")
+	userUUID := _userUUID.(uuid.UUID)
+
+	err := a.repo.MarkAttendance(requestBody.SessionID, requestBody.Marks, userUUID)
+	if errors.Is(err, repository.ErrNotInRoster) || errors.Is(err, repository.ErrDuplicateMark) {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if errors.Is(err, repository.ErrSessionCancelled) {
+		c.String(http.StatusConflict, err.Error())
+		return
+	}
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.String(http.StatusOK, "Посещаемость отмечена")
+}
+
+// @Summary      Моя посещаемость
+// @Description  Возвращает прошедшие занятия групп студента с его отметками
+// @Tags         Посещаемость
+// @Produce      json
+// @Success      200  {array}  ds.StudentAttendance
+// @Param term query string false "id семестра или all (по умолчанию текущий семестр)"
+// @Router       /attendance/my [get]
+func (a *Application) get_my_attendance(c *gin.Context) {
+	term_id, ok := a.parseTermFilter(c, a.repo.GetCurrentTerm)
+	if !ok {
+		return
+	}
+
+	_userUUID, _ := c.Get("userUUID")
+	userUUID := _userUUID.(uuid.UUID)
+
+	attendance, err := a.repo.GetUserAttendance(userUUID, term_id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, attendance)
+}
+
+// @Summary      Посещаемость группы
+// @Description  Возвращает статистику посещаемости зачисленных в группу студентов по прошедшим занятиям
+// @Tags         Посещаемость
+// @Produce      json
+// @Success      200  {object}  ds.GroupAttendanceStats
+// @Param group_id path int true "id группы"
+// @Router       /group/attendance/{group_id} [get]
+func (a *Application) get_group_attendance_stats(c *gin.Context) {
+	group_id, err := strconv.Atoi(c.Param("group_id"))
+	if err != nil {
+		c.String(http.StatusBadRequest, "Не получается прочитать ID группы")
+		return
+	}
+
+	if !a.attendanceGroup(c, group_id) {
+		return
+	}
+
+	stats, err := a.repo.GetGroupAttendanceStats(group_id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
+
+// attendanceSession находит занятие и проверяет, что текущий пользователь
+// может работать с посещаемостью его группы.
+func (a *Application) attendanceSession(c *gin.Context, session_id int) (*ds.SessionInstance, bool) {
+	instance, err := a.repo.GetSessionInstance(session_id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.String(http.StatusNotFound, "Занятие не найдено")
+		return nil, false
+	}
+
+	if err != nil {
+		c.Error(err)
+		return nil, false
+	}
+
+	if !a.attendanceGroup(c, instance.GroupRefer) {
+		return nil, false
+	}
+
+	return instance, true
+}
+
+// attendanceGroup пропускает модераторов и администраторов, а тренера -
+// только к группам, которые он ведёт.
+func (a *Application) attendanceGroup(c *gin.Context, group_id int) bool {
+	_roleNumber, _ := c.Get("role")
+	if roleNumber, ok := _roleNumber.(role.Role); !ok || roleNumber != role.Coach {
+		return true
+	}
+
+	coach, ok := a.currentCoach(c)
+	if !ok {
+		return false
+	}
+
+	_, ok = a.coachGroup(c, coach, group_id)
+	return ok
+}
+
+// parsePeriod читает необязательные параметры from и to в формате ГГГГ-ММ-ДД.
+func parsePeriod(c *gin.Context) (time.Time, time.Time, error) {
+	var from, to time.Time
+	var err error
+
+	if param := c.Query("from"); param != "" {
+		if from, err = time.Parse("2006-01-02", param); err != nil {
+			return from, to, errors.New("дата from должна быть в формате ГГГГ-ММ-ДД")
+		}
+	}
+
+	if param := c.Query("to"); param != "" {
+		if to, err = time.Parse("2006-01-02", param); err != nil {
+			return from, to, errors.New("дата to должна быть в формате ГГГГ-ММ-ДД")
+		}
+	}
+
+	return from, to, nil
+}
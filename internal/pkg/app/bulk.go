@@ -0,0 +1,269 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"sports_courses/internal/app/ds"
+	"sports_courses/internal/app/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	// bulkSyncLimit - сколько записей можно обработать за один синхронный запрос
+	bulkSyncLimit = 200
+	// bulkProgressStep - через сколько записей фоновая задача сохраняет прогресс
+	bulkProgressStep = 50
+)
+
+// @Summary      Массовая модерация записей
+// @Description  Одобряет или отклоняет перечисленные записи либо все сформированные записи, подходящие под фильтр. Каждая запись обрабатывается в своей транзакции, в ответе итог по каждой. Модератор берёт каждую запись в работу сам, записи, взятые в работу другим модератором, пропускаются. С Async=true задача выполняется в фоне, а в ответе возвращается её ID
+// @Tags         Модерация
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  ds.BulkModerationReport
+// @Success      202  {object}  ds.BulkModerationJob
+// @Param request_body body ds.BulkModerationRequestBody true "Записи и решение"
+// @Router       /enrollments/moderate [post]
+func (a *Application) bulk_moderate_enrollments(c *gin.Context) {
+	var requestBody ds.BulkModerationRequestBody
+
+	if err := c.BindJSON(&requestBody); err != nil {
+		c.String(http.StatusBadRequest, "Не получается распознать json запрос")
+		return
+	}
+
+	rejection := ds.RejectionRequestBody{ReasonCode: requestBody.ReasonCode, Comment: requestBody.Comment}
+	if !requestBody.Confirm {
+		if err := repository.CheckRejection(rejection); err != nil {
+			c.String(http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	if (len(requestBody.EnrollmentIDs) == 0) == (requestBody.Filter == nil) {
+		c.String(http.StatusBadRequest, "Нужно передать либо список записей, либо фильтр")
+		return
+	}
+
+	enrollment_ids := requestBody.EnrollmentIDs
+	if requestBody.Filter != nil {
+		filter := requestBody.Filter
+		ids, err := a.repo.GetFormedEnrollmentIDs(ds.EnrollmentFilter{
+			TermID:       filter.TermID,
+			StudyGroup:   strings.TrimSpace(filter.StudyGroup),
+			Faculty:      strings.TrimSpace(filter.Faculty),
+			Year:         filter.Year,
+			MedicalGroup: filter.MedicalGroup,
+		})
+		if err != nil {
+			c.Error(err)
+			return
+		}
+		enrollment_ids = ids
+	} else {
+		slices.Sort(enrollment_ids)
+		enrollment_ids = slices.Compact(enrollment_ids)
+	}
+
+	if !requestBody.Async && len(enrollment_ids) > bulkSyncLimit {
+		c.String(http.StatusBadRequest, "За один запрос можно обработать не больше "+strconv.Itoa(bulkSyncLimit)+" записей, для большего числа используйте Async")
+		return
+	}
+
+	if !a.redis.Available() {
+		c.String(http.StatusServiceUnavailable, "Не получается проверить аренду записей, попробуйте позже")
+		return
+	}
+
+	userUUID, isAdmin := claimUser(c)
+
+	if !requestBody.Async {
+		report := a.moderateEnrollments(c.Request.Context(), userUUID, isAdmin, enrollment_ids, requestBody.Confirm, rejection, nil)
+		c.JSON(http.StatusOK, report)
+		return
+	}
+
+	job := ds.BulkModerationJob{
+		ModeratorRefer: userUUID,
+		Status:         ds.BulkJobQueued,
+		Confirm:        requestBody.Confirm,
+		ReasonCode:     requestBody.ReasonCode,
+		Comment:        requestBody.Comment,
+		Report:         ds.BulkModerationReport{Total: len(enrollment_ids)},
+		CreatedAt:      time.Now(),
+	}
+
+	if err := a.repo.CreateBulkModerationJob(&job); err != nil {
+		c.Error(err)
+		return
+	}
+
+	go a.runBulkModeration(job, enrollment_ids, isAdmin)
+
+	c.JSON(http.StatusAccepted, job)
+}
+
+// @Summary      Получить задачу массовой модерации
+// @Description  Возвращает статус и прогресс фоновой массовой модерации. Модератор видит только свои задачи
+// @Tags         Модерация
+// @Produce      json
+// @Success      200  {object}  ds.BulkModerationJob
+// @Param job_id path int true "id задачи"
+// @Router       /enrollments/moderate/{job_id} [get]
+func (a *Application) get_bulk_moderation_job(c *gin.Context) {
+	job_id, err := strconv.Atoi(c.Param("job_id"))
+	if err != nil {
+		c.String(http.StatusBadRequest, "Передан некорректный ID задачи")
+		return
+	}
+
+	job, err := a.repo.GetBulkModerationJob(job_id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.String(http.StatusNotFound, "Задача не найдена")
+		return
+	}
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	userUUID, isAdmin := claimUser(c)
+	if !isAdmin && job.ModeratorRefer != userUUID {
+		c.String(http.StatusForbidden, "Это задача другого модератора")
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+// failUnfinishedBulkModeration отмечает прерванными фоновые задачи, которые
+// выполнялись до перезапуска сервиса.
+func (a *Application) failUnfinishedBulkModeration() {
+	count, err := a.repo.FailUnfinishedBulkModerationJobs()
+	if err != nil {
+		log.Println("Не получается отметить прерванные задачи массовой модерации:", err)
+		return
+	}
+
+	if count > 0 {
+		log.Println("Прервано задач массовой модерации:", count)
+	}
+}
+
+// runBulkModeration выполняет фоновую задачу и по ходу сохраняет прогресс.
+func (a *Application) runBulkModeration(job ds.BulkModerationJob, enrollment_ids []uint, isAdmin bool) {
+	ctx := context.Background()
+
+	job.Status = ds.BulkJobRunning
+	if err := a.repo.SaveBulkModerationJob(&job); err != nil {
+		log.Println("Не получается сохранить задачу массовой модерации:", err)
+	}
+
+	rejection := ds.RejectionRequestBody{ReasonCode: job.ReasonCode, Comment: job.Comment}
+	report := a.moderateEnrollments(ctx, job.ModeratorRefer, isAdmin, enrollment_ids, job.Confirm, rejection, func(report ds.BulkModerationReport) {
+		job.Processed = len(report.Results)
+		job.Report = report
+		if err := a.repo.SaveBulkModerationJob(&job); err != nil {
+			log.Println("Не получается сохранить прогресс массовой модерации:", err)
+		}
+	})
+
+	finished := time.Now()
+	job.Status = ds.BulkJobFinished
+	job.Processed = len(report.Results)
+	job.Report = report
+	job.FinishedAt = &finished
+	if err := a.repo.SaveBulkModerationJob(&job); err != nil {
+		log.Println("Не получается сохранить итог массовой модерации:", err)
+	}
+}
+
+// moderateEnrollments обрабатывает записи по одной. progress, если задан,
+// вызывается каждые bulkProgressStep записей.
+func (a *Application) moderateEnrollments(ctx context.Context, moderator uuid.UUID, isAdmin bool, enrollment_ids []uint, confirm bool, rejection ds.RejectionRequestBody, progress func(ds.BulkModerationReport)) ds.BulkModerationReport {
+	report := ds.BulkModerationReport{
+		Total:   len(enrollment_ids),
+		Results: make([]ds.BulkModerationResult, 0, len(enrollment_ids)),
+	}
+
+	for i, enrollment_id := range enrollment_ids {
+		result := a.moderateEnrollment(ctx, moderator, isAdmin, enrollment_id, confirm, rejection)
+		report.Results = append(report.Results, result)
+
+		switch {
+		case result.Error != "":
+			report.Failed++
+		case confirm:
+			report.Approved++
+		default:
+			report.Rejected++
+		}
+
+		if progress != nil && (i+1)%bulkProgressStep == 0 {
+			progress(report)
+		}
+	}
+
+	return report
+}
+
+func (a *Application) moderateEnrollment(ctx context.Context, moderator uuid.UUID, isAdmin bool, enrollment_id uint, confirm bool, rejection ds.RejectionRequestBody) ds.BulkModerationResult {
+	result := ds.BulkModerationResult{EnrollmentID: enrollment_id}
+
+	// модератор берёт запись в работу сам, чтобы никто другой не взял её,
+	// пока принимается решение; чужие записи пропускаются
+	if !isAdmin {
+		holder, _, err := a.redis.ClaimEnrollment(ctx, enrollment_id, moderator, a.config.Moderation.ClaimTTL, false)
+		if err != nil {
+			log.Println("Не получается взять запись в работу:", err)
+			result.Error = "Не получается взять запись в работу"
+			return result
+		}
+
+		if holder != moderator {
+			result.Error = "Запись в работе у другого модератора"
+			return result
+		}
+	}
+
+	status, err := a.repo.ModeratorConfirmEnrollment(moderator, enrollment_id, confirm, rejection)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		a.releaseProcessedClaim(ctx, enrollment_id)
+		result.Error = "Запись не найдена"
+		return result
+	}
+
+	if errors.Is(err, repository.ErrEnrollmentProcessed) {
+		a.releaseProcessedClaim(ctx, enrollment_id)
+		result.Error = err.Error()
+		return result
+	}
+
+	if err != nil {
+		log.Println("Не получается обновить статус записи", enrollment_id, ":", err)
+		// запись не обработана, поэтому снимается только своя аренда, чтобы её
+		// сразу могли взять другие модераторы
+		if err := a.redis.ReleaseEnrollmentClaim(ctx, enrollment_id, moderator, false); err != nil {
+			log.Println("Не получается снять аренду с записи:", err)
+		}
+		result.Error = "Не получается обновить статус"
+		return result
+	}
+
+	result.Status = status
+	a.releaseProcessedClaim(ctx, enrollment_id)
+	a.publishEnrollmentStatus(ctx, enrollment_id, status, &moderator)
+
+	return result
+}
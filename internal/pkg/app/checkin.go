@@ -0,0 +1,253 @@
+package app
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"sports_courses/internal/app/ds"
+	"sports_courses/internal/app/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/skip2/go-qrcode"
+)
+
+const (
+	checkInPayloadSize   = 12
+	checkInSignatureSize = 8
+	checkInQRSize        = 320
+	earthRadiusMeters    = 6371000
+)
+
+var (
+	errInvalidCheckInCode = errors.New("код отметки недействителен")
+	errExpiredCheckInCode = errors.New("срок действия кода истёк, попросите тренера показать новый")
+)
+
+// @Summary      Код отметки на занятии
+// @Description  Создаёт короткоживущий код, по которому студенты сами отмечаются на сегодняшнем занятии. По умолчанию возвращает QR-код в PNG, с format=json - сам код
+// @Tags         Посещаемость
+// @Produce      png
+// @Produce      json
+// @Success      200  {object}  ds.CheckInCode
+// @Param session_id path int true "id занятия"
+// @Param format query string false "json, чтобы получить код без картинки"
+// @Router       /session/checkin/{session_id} [get]
+func (a *Application) get_checkin_code(c *gin.Context) {
+	session_id, err := strconv.Atoi(c.Param("session_id"))
+	if err != nil {
+		c.String(http.StatusBadRequest, "Не получается прочитать ID занятия")
+		return
+	}
+
+	instance, ok := a.attendanceSession(c, session_id)
+	if !ok {
+		return
+	}
+
+	if instance.Cancelled {
+		c.String(http.StatusConflict, "Занятие отменено")
+		return
+	}
+
+	if instance.Date.Format("2006-01-02") != time.Now().Format("2006-01-02") {
+		c.String(http.StatusBadRequest, "Код можно получить только в день занятия")
+		return
+	}
+
+	code, err := a.signCheckInCode(instance.ID, time.Now())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	if c.Query("format") == "json" {
+		c.JSON(http.StatusOK, code)
+		return
+	}
+
+	png, err := qrcode.Encode(code.Code, qrcode.Medium, checkInQRSize)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.Header("X-Checkin-Expires", code.ExpiresAt.Format(time.RFC3339))
+	c.Data(http.StatusOK, "image/png", png)
+}
+
+// @Summary      Отметиться на занятии
+// @Description  Студент отмечает себя присутствующим по коду, который показал тренер
+// @Tags         Посещаемость
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  string
+// @Param request_body body ds.CheckInRequestBody true "Код и координаты"
+// @Router       /attendance/checkin [post]
+func (a *Application) checkin(c *gin.Context) {
+	var requestBody ds.CheckInRequestBody
+
+	if err := c.BindJSON(&requestBody); err != nil || requestBody.Code == "" {
+		c.String(http.StatusBadRequest, "Не получается распознать json запрос")
+		return
+	}
+
+	session_id, err := a.parseCheckInCode(requestBody.Code, time.Now())
+	if err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	instance, err := a.repo.GetSessionInstance(int(session_id))
+	if err != nil {
+		c.String(http.StatusBadRequest, errInvalidCheckInCode.Error())
+		return
+	}
+
+	if instance.Cancelled {
+		c.String(http.StatusConflict, "Занятие отменено")
+		return
+	}
+
+	if !a.withinGeofence(c, instance, requestBody) {
+		return
+	}
+
+	_userUUID, _ := c.Get("userUUID")
+	userUUID := _userUUID.(uuid.UUID)
+
+	// код тратится только на тех, кто может отметиться на занятии, чтобы
+	// посторонний не мог израсходовать его раньше студентов группы
+	member, err := a.repo.IsGroupMember(instance.GroupRefer, userUUID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	if !member {
+		c.String(http.StatusForbidden, "Вы не зачислены в группу этого занятия")
+		return
+	}
+
+	claimed, err := a.redis.ClaimCheckIn(c.Request.Context(), requestBody.Code, userUUID, a.config.CheckIn.CodeTTL)
+	if err != nil {
+		log.Println("Не получается проверить повторное использование кода:", err)
+		c.String(http.StatusServiceUnavailable, "Не получается проверить код, попробуйте позже")
+		return
+	}
+
+	if !claimed {
+		c.String(http.StatusConflict, "Вы уже отметились по этому коду")
+		return
+	}
+
+	err = a.repo.MarkAttendance(instance.ID, []ds.AttendanceMark{{UserUUID: userUUID, Status: ds.AttendancePresent}}, userUUID)
+	if errors.Is(err, repository.ErrNotInRoster) {
+		c.String(http.StatusForbidden, "Вы не зачислены в группу этого занятия")
+		return
+	}
+
+	if errors.Is(err, repository.ErrSessionCancelled) {
+		c.String(http.StatusConflict, "Занятие отменено")
+		return
+	}
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.String(http.StatusOK, "Вы отмечены на занятии")
+}
+
+// withinGeofence проверяет, что студент находится рядом с местом занятия.
+// Проверка пропускается, если она отключена или у места нет координат.
+func (a *Application) withinGeofence(c *gin.Context, instance *ds.SessionInstance, requestBody ds.CheckInRequestBody) bool {
+	radius := a.config.CheckIn.GeofenceRadius
+	location := instance.Location
+	if radius <= 0 || location == nil || (location.Latitude == 0 && location.Longitude == 0) {
+		return true
+	}
+
+	if requestBody.Latitude == nil || requestBody.Longitude == nil {
+		c.String(http.StatusBadRequest, "Чтобы отметиться, нужно передать координаты")
+		return false
+	}
+
+	distance := haversine(*requestBody.Latitude, *requestBody.Longitude, location.Latitude, location.Longitude)
+	if distance > radius {
+		c.String(http.StatusForbidden, "Вы находитесь слишком далеко от места занятия")
+		return false
+	}
+
+	return true
+}
+
+// signCheckInCode собирает код из id занятия, срока действия и случайной
+// соли и подписывает его HMAC, поэтому код нельзя подделать без секрета.
+func (a *Application) signCheckInCode(session_id uint, now time.Time) (ds.CheckInCode, error) {
+	expiresAt := now.Add(a.config.CheckIn.CodeTTL)
+
+	data := make([]byte, checkInPayloadSize, checkInPayloadSize+checkInSignatureSize)
+	binary.BigEndian.PutUint32(data[0:4], uint32(session_id))
+	binary.BigEndian.PutUint32(data[4:8], uint32(expiresAt.Unix()))
+	if _, err := rand.Read(data[8:12]); err != nil {
+		return ds.CheckInCode{}, err
+	}
+
+	data = append(data, a.checkInSignature(data)...)
+
+	return ds.CheckInCode{
+		SessionID: session_id,
+		Code:      base64.RawURLEncoding.EncodeToString(data),
+		ExpiresAt: time.Unix(expiresAt.Unix(), 0),
+	}, nil
+}
+
+// parseCheckInCode проверяет подпись и срок действия кода и возвращает id занятия.
+func (a *Application) parseCheckInCode(code string, now time.Time) (uint, error) {
+	data, err := base64.RawURLEncoding.DecodeString(code)
+	if err != nil || len(data) != checkInPayloadSize+checkInSignatureSize {
+		return 0, errInvalidCheckInCode
+	}
+
+	payload, signature := data[:checkInPayloadSize], data[checkInPayloadSize:]
+	if !hmac.Equal(signature, a.checkInSignature(payload)) {
+		return 0, errInvalidCheckInCode
+	}
+
+	if now.Unix() > int64(binary.BigEndian.Uint32(payload[4:8])) {
+		return 0, errExpiredCheckInCode
+	}
+
+	return uint(binary.BigEndian.Uint32(payload[0:4])), nil
+}
+
+func (a *Application) checkInSignature(payload []byte) []byte {
+	mac := hmac.New(sha256.New, []byte(a.config.CheckIn.Secret))
+	mac.Write(payload)
+	return mac.Sum(nil)[:checkInSignatureSize]
+}
+
+// haversine возвращает расстояние в метрах между двумя точками на Земле.
+func haversine(lat1, lon1, lat2, lon2 float64) float64 {
+	toRad := func(deg float64) float64 { return deg * math.Pi / 180 }
+
+	dLat := toRad(lat2 - lat1)
+	dLon := toRad(lon2 - lon1)
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRad(lat1))*math.Cos(toRad(lat2))*math.Sin(dLon/2)*math.Sin(dLon/2)
+
+	return 2 * earthRadiusMeters * math.Asin(math.Sqrt(h))
+}
@@ -0,0 +1,182 @@
+package app
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"sports_courses/internal/app/ds"
+	"sports_courses/internal/app/role"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// @Summary      Взять запись в работу
+// @Description  Закрепляет сформированную запись за модератором на время аренды, повторный вызов продлевает её. Пока аренда действует, другие модераторы получают 409. Администратор может перебить чужую аренду с force=true
+// @Tags         Модерация
+// @Produce      json
+// @Success      200  {object}  ds.EnrollmentClaim
+// @Failure      409  {object}  ds.EnrollmentClaim
+// @Param enrollment_id path int true "id записи"
+// @Param force query bool false "Перебить чужую аренду (только администратор)"
+// @Router       /enrollment/claim/{enrollment_id} [put]
+func (a *Application) claim_enrollment(c *gin.Context) {
+	enrollment_id, err := strconv.Atoi(c.Param("enrollment_id"))
+	if err != nil {
+		c.String(http.StatusBadRequest, "Передан некорректный ID записи")
+		return
+	}
+
+	userUUID, isAdmin := claimUser(c)
+	force := c.Query("force") == "true"
+	if force && !isAdmin {
+		c.String(http.StatusForbidden, "Перебить чужую аренду может только администратор")
+		return
+	}
+
+	status, err := a.repo.GetEnrollmentStatus(enrollment_id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	if status == "" {
+		c.String(http.StatusNotFound, "Запись не найдена")
+		return
+	}
+
+	if status != "Сформирован" {
+		c.String(http.StatusConflict, "Запись уже обработана")
+		return
+	}
+
+	ttl := a.config.Moderation.ClaimTTL
+	holder, left, err := a.redis.ClaimEnrollment(c.Request.Context(), uint(enrollment_id), userUUID, ttl, force)
+	if err != nil {
+		log.Println("Не получается взять запись в работу:", err)
+		c.String(http.StatusServiceUnavailable, "Не получается взять запись в работу, попробуйте позже")
+		return
+	}
+
+	claim := ds.EnrollmentClaim{
+		EnrollmentID: uint(enrollment_id),
+		Moderator:    holder,
+		ExpiresAt:    time.Now().Add(left),
+	}
+
+	if holder != userUUID {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "Запись в работе у другого модератора",
+			"claim": claim,
+		})
+		return
+	}
+
+	if err := a.repo.SetEnrollmentModerator(enrollment_id, userUUID); err != nil {
+		c.Error(err)
+		return
+	}
+
+	a.publishModerationEvent(c.Request.Context(), ds.ModerationEvent{
+		Type:         ds.QueueEnrollmentClaimed,
+		EnrollmentID: claim.EnrollmentID,
+		Moderator:    &claim.Moderator,
+		ExpiresAt:    &claim.ExpiresAt,
+	})
+
+	c.JSON(http.StatusOK, claim)
+}
+
+// @Summary      Вернуть запись в очередь
+// @Description  Снимает аренду модератора с записи. Администратор может снять чужую аренду
+// @Tags         Модерация
+// @Produce      json
+// @Success      200  {object}  string
+// @Param enrollment_id path int true "id записи"
+// @Router       /enrollment/claim/{enrollment_id} [delete]
+func (a *Application) release_enrollment_claim(c *gin.Context) {
+	enrollment_id, err := strconv.Atoi(c.Param("enrollment_id"))
+	if err != nil {
+		c.String(http.StatusBadRequest, "Передан некорректный ID записи")
+		return
+	}
+
+	userUUID, isAdmin := claimUser(c)
+
+	holder, _, err := a.redis.EnrollmentClaim(c.Request.Context(), uint(enrollment_id))
+	if err != nil {
+		log.Println("Не получается проверить аренду записи:", err)
+		c.String(http.StatusServiceUnavailable, "Не получается проверить аренду записи, попробуйте позже")
+		return
+	}
+
+	if holder == uuid.Nil {
+		c.String(http.StatusOK, "Запись никто не держит")
+		return
+	}
+
+	if holder != userUUID && !isAdmin {
+		c.String(http.StatusConflict, "Запись в работе у другого модератора")
+		return
+	}
+
+	if err := a.redis.ReleaseEnrollmentClaim(c.Request.Context(), uint(enrollment_id), holder, isAdmin); err != nil {
+		c.Error(err)
+		return
+	}
+
+	a.publishModerationEvent(c.Request.Context(), ds.ModerationEvent{
+		Type:         ds.QueueEnrollmentReleased,
+		EnrollmentID: uint(enrollment_id),
+		Moderator:    &holder,
+	})
+
+	c.String(http.StatusOK, "Запись возвращена в очередь")
+}
+
+// holdsClaim проверяет, что текущий модератор держит запись. Администратор
+// может принять решение по записи и без аренды.
+func (a *Application) holdsClaim(c *gin.Context, enrollment_id uint) bool {
+	userUUID, isAdmin := claimUser(c)
+	if isAdmin {
+		return true
+	}
+
+	holder, _, err := a.redis.EnrollmentClaim(c.Request.Context(), enrollment_id)
+	if err != nil {
+		log.Println("Не получается проверить аренду записи:", err)
+		c.String(http.StatusServiceUnavailable, "Не получается проверить аренду записи, попробуйте позже")
+		return false
+	}
+
+	if holder == uuid.Nil {
+		c.String(http.StatusConflict, "Сначала возьмите запись в работу")
+		return false
+	}
+
+	if holder != userUUID {
+		c.String(http.StatusConflict, "Запись в работе у другого модератора")
+		return false
+	}
+
+	return true
+}
+
+// releaseProcessedClaim снимает аренду с записи, по которой принято решение.
+func (a *Application) releaseProcessedClaim(ctx context.Context, enrollment_id uint) {
+	if err := a.redis.ReleaseEnrollmentClaim(ctx, enrollment_id, uuid.Nil, true); err != nil {
+		log.Println("Не получается снять аренду с обработанной записи:", err)
+	}
+}
+
+func claimUser(c *gin.Context) (uuid.UUID, bool) {
+	_userUUID, _ := c.Get("userUUID")
+	_userRole, _ := c.Get("role")
+
+	userRole, _ := _userRole.(role.Role)
+
+	return _userUUID.(uuid.UUID), userRole == role.Admin
+}
@@ -0,0 +1,357 @@
+package app
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"sports_courses/internal/app/ds"
+	"sports_courses/internal/app/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// @Summary      Получить тренеров
+// @Description  Возвращает список тренеров
+// @Tags         Тренеры
+// @Produce      json
+// @Success      200  {object}  string
+// @Param limit query int false "Размер страницы"
+// @Param offset query int false "Смещение от начала списка"
+// @Param cursor query string false "Курсор следующей страницы из next_cursor"
+// @Param sort query string false "Сортировка, например name"
+// @Router       /coaches [get]
+func (a *Application) get_coaches(c *gin.Context) {
+	page, err := parsePageRequest(c, repository.CoachSortColumns)
+	if err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	coaches, info, err := a.repo.GetCoaches(page)
+	if isPageError(err) {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, pageEnvelope(c, coaches, page, info))
+}
+
+// @Summary      Добавить тренера
+// @Description  Создаёт тренера и, если передан логин, связывает его с аккаунтом пользователя
+// @Tags         Тренеры
+// @Accept       json
+// @Produce      json
+// @Success      201  {object}  ds.Coach
+// @Param request_body body ds.CoachRequestBody true "Данные тренера"
+// @Router       /coach/add [post]
+func (a *Application) add_coach(c *gin.Context) {
+	var requestBody ds.CoachRequestBody
+
+	if err := c.BindJSON(&requestBody); err != nil || strings.TrimSpace(requestBody.Name) == "" {
+		c.String(http.StatusBadRequest, "Не получается распознать тренера")
+		return
+	}
+
+	coach := &ds.Coach{
+		Name:  strings.TrimSpace(requestBody.Name),
+		Phone: requestBody.Phone,
+		Email: requestBody.Email,
+	}
+
+	if !coachLinked(c, a.repo.CreateCoach(coach, requestBody.Login)) {
+		return
+	}
+
+	c.JSON(http.StatusCreated, coach)
+}
+
+// @Summary      Редактировать тренера
+// @Description  Обновляет контакты тренера
+// @Tags         Тренеры
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  string
+// @Param request_body body ds.CoachRequestBody true "Данные тренера"
+// @Router       /coach/edit [put]
+func (a *Application) edit_coach(c *gin.Context) {
+	var requestBody ds.CoachRequestBody
+
+	if err := c.BindJSON(&requestBody); err != nil {
+		c.String(http.StatusBadRequest, "Передан плохой json")
+		return
+	}
+
+	err := a.repo.EditCoach(&ds.Coach{
+		ID:    uint(requestBody.CoachID),
+		Name:  strings.TrimSpace(requestBody.Name),
+		Phone: requestBody.Phone,
+		Email: requestBody.Email,
+	})
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	a.invalidateGroupsCache(c.Request.Context())
+
+	c.String(http.StatusOK, "Тренер был успешно изменён")
+}
+
+// @Summary      Привязать аккаунт к тренеру
+// @Description  Связывает тренера с пользователем и выдаёт пользователю роль тренера
+// @Tags         Тренеры
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  string
+// @Param request_body body ds.CoachRequestBody true "CoachID и Login"
+// @Router       /coach/link [put]
+func (a *Application) link_coach_user(c *gin.Context) {
+	var requestBody ds.CoachRequestBody
+
+	if err := c.BindJSON(&requestBody); err != nil || requestBody.Login == "" {
+		c.String(http.StatusBadRequest, "Нужно передать CoachID и Login")
+		return
+	}
+
+	if !a.linkCoachUser(c, requestBody.CoachID, requestBody.Login) {
+		return
+	}
+
+	c.String(http.StatusOK, "Аккаунт привязан к тренеру")
+}
+
+func (a *Application) linkCoachUser(c *gin.Context, coach_id int, login string) bool {
+	return coachLinked(c, a.repo.LinkCoachUser(coach_id, login))
+}
+
+// coachLinked отвечает клиенту, если тренера не удалось сохранить или
+// связать с аккаунтом.
+func coachLinked(c *gin.Context, err error) bool {
+	if errors.Is(err, repository.ErrUserNotFound) {
+		c.String(http.StatusNotFound, err.Error())
+		return false
+	}
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.String(http.StatusNotFound, "Тренер не найден")
+		return false
+	}
+
+	if errors.Is(err, repository.ErrUserNotStudent) {
+		c.String(http.StatusConflict, err.Error())
+		return false
+	}
+
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Не получается сохранить тренера")
+		log.Println(err)
+		return false
+	}
+
+	return true
+}
+
+// currentCoach находит тренера, привязанного к текущему пользователю.
+func (a *Application) currentCoach(c *gin.Context) (*ds.Coach, bool) {
+	_userUUID, _ := c.Get("userUUID")
+	userUUID := _userUUID.(uuid.UUID)
+
+	coach, err := a.repo.GetCoachByUser(userUUID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.String(http.StatusForbidden, "Аккаунт не привязан к тренеру")
+		return nil, false
+	}
+
+	if err != nil {
+		c.Error(err)
+		return nil, false
+	}
+
+	return coach, true
+}
+
+// coachGroup проверяет, что группа ведётся текущим тренером.
+func (a *Application) coachGroup(c *gin.Context, coach *ds.Coach, group_id int) (*ds.Group, bool) {
+	group, err := a.repo.GetGroupByID(group_id)
+	if err != nil {
+		c.Error(err)
+		return nil, false
+	}
+
+	if group.ID == 0 || group.CoachRefer == nil || *group.CoachRefer != coach.ID {
+		c.String(http.StatusForbidden, "Группа ведётся другим тренером")
+		return nil, false
+	}
+
+	return group, true
+}
+
+// @Summary      Профиль тренера
+// @Description  Возвращает данные тренера, привязанного к текущему пользователю
+// @Tags         Тренеры
+// @Produce      json
+// @Success      200  {object}  ds.Coach
+// @Router       /coach/me [get]
+func (a *Application) get_coach_me(c *gin.Context) {
+	coach, ok := a.currentCoach(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, coach)
+}
+
+// @Summary      Изменить свои контакты
+// @Description  Тренер обновляет своё имя, телефон и почту
+// @Tags         Тренеры
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  string
+// @Param request_body body ds.EditCoachContactsRequestBody true "Контакты"
+// @Router       /coach/me [put]
+func (a *Application) edit_coach_me(c *gin.Context) {
+	var requestBody ds.EditCoachContactsRequestBody
+
+	if err := c.BindJSON(&requestBody); err != nil {
+		c.String(http.StatusBadRequest, "Передан плохой json")
+		return
+	}
+
+	coach, ok := a.currentCoach(c)
+	if !ok {
+		return
+	}
+
+	coach.Name = strings.TrimSpace(requestBody.Name)
+	coach.Phone = requestBody.Phone
+	coach.Email = requestBody.Email
+
+	if coach.Name == "" {
+		c.String(http.StatusBadRequest, "Имя тренера не может быть пустым")
+		return
+	}
+
+	err := a.repo.EditCoach(coach)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	a.invalidateGroupsCache(c.Request.Context())
+
+	c.String(http.StatusOK, "Контакты обновлены")
+}
+
+// @Summary      Группы тренера
+// @Description  Возвращает группы, которые ведёт текущий тренер
+// @Tags         Тренеры
+// @Produce      json
+// @Success      200  {array}  ds.Group
+// @Router       /coach/groups [get]
+func (a *Application) get_coach_groups(c *gin.Context) {
+	coach, ok := a.currentCoach(c)
+	if !ok {
+		return
+	}
+
+	groups, err := a.repo.GetCoachGroups(coach.ID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, groups)
+}
+
+// @Summary      Состав группы
+// @Description  Возвращает завершённые записи в группу тренера вместе со студентами
+// @Tags         Тренеры
+// @Produce      json
+// @Success      200  {array}  ds.Enrollment
+// @Param group_id path int true "id группы"
+// @Router       /coach/roster/{group_id} [get]
+func (a *Application) get_coach_roster(c *gin.Context) {
+	a.coachGroupEnrollments(c, "Завершён")
+}
+
+// @Summary      Лист ожидания группы
+// @Description  Возвращает сформированные, но ещё не обработанные модератором записи в группу тренера
+// @Tags         Тренеры
+// @Produce      json
+// @Success      200  {array}  ds.Enrollment
+// @Param group_id path int true "id группы"
+// @Router       /coach/waitlist/{group_id} [get]
+func (a *Application) get_coach_waitlist(c *gin.Context) {
+	a.coachGroupEnrollments(c, "Сформирован")
+}
+
+func (a *Application) coachGroupEnrollments(c *gin.Context, status string) {
+	group_id, err := strconv.Atoi(c.Param("group_id"))
+	if err != nil {
+		c.String(http.StatusBadRequest, "Не получается прочитать ID группы")
+		return
+	}
+
+	coach, ok := a.currentCoach(c)
+	if !ok {
+		return
+	}
+
+	if _, ok := a.coachGroup(c, coach, group_id); !ok {
+		return
+	}
+
+	enrollments, err := a.repo.GetGroupRoster(group_id, status)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, enrollments)
+}
+
+// @Summary      Изменить описание группы
+// @Description  Тренер обновляет описание своей группы
+// @Tags         Тренеры
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  string
+// @Param request_body body ds.EditGroupDescriptionRequestBody true "Описание"
+// @Router       /coach/group/description [put]
+func (a *Application) edit_coach_group_description(c *gin.Context) {
+	var requestBody ds.EditGroupDescriptionRequestBody
+
+	if err := c.BindJSON(&requestBody); err != nil {
+		c.String(http.StatusBadRequest, "Передан плохой json")
+		return
+	}
+
+	coach, ok := a.currentCoach(c)
+	if !ok {
+		return
+	}
+
+	if _, ok := a.coachGroup(c, coach, requestBody.GroupID); !ok {
+		return
+	}
+
+	err := a.repo.SetGroupDescription(requestBody.GroupID, requestBody.Description)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	a.invalidateGroupsCache(c.Request.Context())
+
+	c.String(http.StatusOK, "Описание группы обновлено")
+}
@@ -0,0 +1,136 @@
+package app
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"sports_courses/internal/app/ds"
+	"sports_courses/internal/app/role"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// @Summary      Получить причины отклонения
+// @Description  Возвращает коды причин отклонения записи и их описания
+// @Tags         Модерация
+// @Produce      json
+// @Success      200  {object}  map[string]string
+// @Router       /rejection_reasons [get]
+func (a *Application) get_rejection_reasons(c *gin.Context) {
+	c.JSON(http.StatusOK, ds.RejectionReasons)
+}
+
+// @Summary      Получить обсуждение записи
+// @Description  Возвращает сообщения по записи от старых к новым. Студент видит только публичные сообщения своей записи, модератор - также внутренние заметки
+// @Tags         Записи
+// @Produce      json
+// @Success      200  {object}  []ds.EnrollmentComment
+// @Param enrollment_id path int true "id записи"
+// @Router       /enrollment/comments/{enrollment_id} [get]
+func (a *Application) get_enrollment_comments(c *gin.Context) {
+	enrollment_id, ok := a.commentEnrollment(c)
+	if !ok {
+		return
+	}
+
+	_userRole, _ := c.Get("role")
+	userRole := _userRole.(role.Role)
+
+	comments, err := a.repo.GetEnrollmentComments(enrollment_id, userRole != role.User)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, comments)
+}
+
+// @Summary      Написать в обсуждение записи
+// @Description  Добавляет сообщение к записи и уведомляет другую сторону. Внутренние заметки могут оставлять только модераторы
+// @Tags         Записи
+// @Accept       json
+// @Produce      json
+// @Success      201  {object}  ds.EnrollmentComment
+// @Param enrollment_id path int true "id записи"
+// @Param request_body body ds.EnrollmentCommentRequestBody true "Сообщение"
+// @Router       /enrollment/comments/{enrollment_id} [post]
+func (a *Application) add_enrollment_comment(c *gin.Context) {
+	var requestBody ds.EnrollmentCommentRequestBody
+
+	if err := c.BindJSON(&requestBody); err != nil {
+		c.String(http.StatusBadRequest, "Не получается распознать json запрос")
+		return
+	}
+
+	requestBody.Body = strings.TrimSpace(requestBody.Body)
+	if requestBody.Body == "" {
+		c.String(http.StatusBadRequest, "Сообщение не может быть пустым")
+		return
+	}
+
+	_userUUID, _ := c.Get("userUUID")
+	_userRole, _ := c.Get("role")
+
+	userUUID := _userUUID.(uuid.UUID)
+	userRole := _userRole.(role.Role)
+
+	if requestBody.Internal && userRole == role.User {
+		c.String(http.StatusForbidden, "Внутренние заметки могут оставлять только модераторы")
+		return
+	}
+
+	enrollment_id, ok := a.commentEnrollment(c)
+	if !ok {
+		return
+	}
+
+	comment := ds.EnrollmentComment{
+		EnrollmentRefer: enrollment_id,
+		AuthorRefer:     userUUID,
+		Internal:        requestBody.Internal,
+		Body:            requestBody.Body,
+	}
+
+	if err := a.repo.AddEnrollmentComment(&comment); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, comment)
+}
+
+// commentEnrollment проверяет, что запись существует и что студент
+// обращается к своей записи. Модераторы видят обсуждения всех записей.
+func (a *Application) commentEnrollment(c *gin.Context) (uint, bool) {
+	enrollment_id, err := strconv.Atoi(c.Param("enrollment_id"))
+	if err != nil {
+		c.String(http.StatusBadRequest, "Передан некорректный ID записи")
+		return 0, false
+	}
+
+	_userUUID, _ := c.Get("userUUID")
+	_userRole, _ := c.Get("role")
+
+	userUUID := _userUUID.(uuid.UUID)
+	userRole := _userRole.(role.Role)
+
+	enrollment, err := a.repo.FindEnrollment(&ds.Enrollment{ID: uint(enrollment_id)}, ds.EnrollmentExpand{})
+	if err != nil {
+		c.Error(err)
+		return 0, false
+	}
+
+	if enrollment.ID == 0 {
+		c.String(http.StatusNotFound, "Запись не найдена")
+		return 0, false
+	}
+
+	if userRole == role.User && (enrollment.UserRefer == nil || *enrollment.UserRefer != userUUID) {
+		c.String(http.StatusForbidden, "Можно обсуждать только свою запись")
+		return 0, false
+	}
+
+	return enrollment.ID, true
+}
@@ -0,0 +1,215 @@
+package app
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"sports_courses/internal/app/ds"
+	"sports_courses/internal/app/repository"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const courseImagesBucket = "courseimages"
+
+// @Summary      Получить курсы
+// @Description  Возвращает курсы с количеством действующих групп и свободных мест в них
+// @Tags         Курсы
+// @Produce      json
+// @Success      200  {object}  string
+// @Param limit query int false "Размер страницы"
+// @Param offset query int false "Смещение от начала списка"
+// @Param cursor query string false "Курсор следующей страницы из next_cursor"
+// @Param sort query string false "Сортировка, например name"
+// @Router       /courses [get]
+func (a *Application) get_courses(c *gin.Context) {
+	page, err := parsePageRequest(c, repository.CourseSortColumns)
+	if err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	courses, info, err := a.repo.GetCourses(page)
+	if isPageError(err) {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, pageEnvelope(c, courses, page, info))
+}
+
+// @Summary      Получить курс
+// @Description  Возвращает курс по его id
+// @Tags         Курсы
+// @Produce      json
+// @Success      200  {object}  ds.Course
+// @Param course_id path int true "id курса"
+// @Router       /course/{course_id} [get]
+func (a *Application) get_course(c *gin.Context) {
+	course_id, err := strconv.Atoi(c.Param("course_id"))
+	if err != nil {
+		c.String(http.StatusBadRequest, "Не получается прочитать ID курса")
+		return
+	}
+
+	course, err := a.repo.GetCourseByID(course_id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.String(http.StatusNotFound, "Курс не найден")
+		return
+	}
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, course)
+}
+
+// @Summary      Добавить курс
+// @Description  Создаёт новый курс
+// @Tags         Курсы
+// @Accept       json
+// @Produce      json
+// @Success      201  {object}  ds.Course
+// @Param course body ds.Course true "Курс"
+// @Router       /course/add [post]
+func (a *Application) add_course(c *gin.Context) {
+	var course ds.Course
+
+	if err := c.BindJSON(&course); err != nil {
+		c.String(http.StatusBadRequest, "Не получается распознать курс")
+		return
+	}
+
+	course.ID = 0
+	course.ImageName = ""
+	course.Name = strings.TrimSpace(course.Name)
+	if course.Name == "" {
+		c.String(http.StatusBadRequest, "Название курса не может быть пустым")
+		return
+	}
+
+	err := a.repo.CreateCourse(&course)
+	if err != nil {
+		c.String(http.StatusConflict, "Не получается создать курс\n"+err.Error())
+		return
+	}
+
+	c.JSON(http.StatusCreated, course)
+}
+
+// @Summary      Редактировать курс
+// @Description  Находит курс по id и обновляет переданные поля
+// @Tags         Курсы
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  string
+// @Param course body ds.Course true "Курс (должен содержать id)"
+// @Router       /course/edit [put]
+func (a *Application) edit_course(c *gin.Context) {
+	var course ds.Course
+
+	if err := c.BindJSON(&course); err != nil || course.ID == 0 {
+		c.String(http.StatusBadRequest, "Не получается распознать курс")
+		return
+	}
+
+	course.Name = strings.TrimSpace(course.Name)
+
+	err := a.repo.EditCourse(&course)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	a.invalidateGroupsCache(c.Request.Context())
+
+	c.String(http.StatusOK, "Курс был успешно изменён")
+}
+
+// @Summary      Удалить курс
+// @Description  Удаляет курс, на который не ссылается ни одна группа
+// @Tags         Курсы
+// @Produce      json
+// @Success      200  {object}  string
+// @Param course_id path int true "id курса"
+// @Router       /course/delete/{course_id} [delete]
+func (a *Application) delete_course(c *gin.Context) {
+	course_id, err := strconv.Atoi(c.Param("course_id"))
+	if err != nil {
+		c.String(http.StatusBadRequest, "Не получается прочитать ID курса")
+		return
+	}
+
+	course, err := a.repo.DeleteCourse(course_id)
+	if errors.Is(err, repository.ErrCourseInUse) {
+		c.String(http.StatusConflict, err.Error())
+		return
+	}
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.String(http.StatusNotFound, "Курс не найден")
+		return
+	}
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	removeObjects(c.Request.Context(), courseImagesBucket, course.ImageName)
+
+	c.String(http.StatusOK, "Курс был успешно удалён")
+}
+
+// @Summary      Загрузить картинку курса
+// @Description  Загружает картинку курса в хранилище, заменяя предыдущую
+// @Tags         Курсы
+// @Accept       multipart/form-data
+// @Produce      json
+// @Success      201  {object}  string
+// @Param course_id path int true "id курса"
+// @Param file formData file true "Картинка"
+// @Router       /course/add_image/{course_id} [post]
+func (a *Application) add_course_image(c *gin.Context) {
+	course_id, err := strconv.Atoi(c.Param("course_id"))
+	if err != nil {
+		c.String(http.StatusBadRequest, "Не получается прочитать ID курса")
+		return
+	}
+
+	course, err := a.repo.GetCourseByID(course_id)
+	if err != nil {
+		c.String(http.StatusNotFound, "Курс не найден")
+		return
+	}
+
+	objectName, err := uploadFormFile(c, courseImagesBucket)
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Не получилось загрузить картинку в minio")
+		log.Println("Не получилось загрузить картинку в minio:", err)
+		return
+	}
+
+	err = a.repo.SetCourseImage(course_id, objectName)
+	if err != nil {
+		removeObjects(c.Request.Context(), courseImagesBucket, objectName)
+		c.String(http.StatusInternalServerError, "Не получается обновить картинку курса")
+		return
+	}
+
+	removeObjects(c.Request.Context(), courseImagesBucket, course.ImageName)
+	a.invalidateGroupsCache(c.Request.Context())
+
+	c.String(http.StatusCreated, "Картинка загружена!")
+}
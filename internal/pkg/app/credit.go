@@ -0,0 +1,227 @@
+package app
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"sports_courses/internal/app/ds"
+	"sports_courses/internal/app/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// @Summary      Мой зачёт
+// @Description  Возвращает зачтённые часы студента за семестр и итог зачёта
+// @Tags         Зачёт
+// @Produce      json
+// @Success      200  {object}  ds.StudentCredit
+// @Param term query string false "id семестра (по умолчанию текущий семестр)"
+// @Router       /me/credits [get]
+func (a *Application) get_my_credits(c *gin.Context) {
+	term_id, ok := a.creditTerm(c)
+	if !ok {
+		return
+	}
+
+	_userUUID, _ := c.Get("userUUID")
+	userUUID := _userUUID.(uuid.UUID)
+
+	credits, err := a.repo.GetStudentCredits(term_id, []uuid.UUID{userUUID})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.String(http.StatusNotFound, "Семестр не найден")
+		return
+	}
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, credits[0])
+}
+
+// @Summary      Зачёт учебной группы
+// @Description  Возвращает зачтённые часы и итог зачёта всех студентов учебной группы. ИУ5 находит все группы кафедры
+// @Tags         Зачёт
+// @Produce      json
+// @Success      200  {array}  ds.StudentCredit
+// @Param study_group query string true "Учебная группа, например ИУ5-31Б"
+// @Param term query string false "id семестра (по умолчанию текущий семестр)"
+// @Router       /credits/report [get]
+func (a *Application) get_credits_report(c *gin.Context) {
+	study_group := strings.TrimSpace(c.Query("study_group"))
+	if study_group == "" {
+		c.String(http.StatusBadRequest, "Нужно указать учебную группу")
+		return
+	}
+
+	term_id, ok := a.creditTerm(c)
+	if !ok {
+		return
+	}
+
+	credits, err := a.repo.GetStudyGroupCredits(term_id, study_group)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.String(http.StatusNotFound, "Семестр не найден")
+		return
+	}
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, credits)
+}
+
+// @Summary      Получить требования к зачёту
+// @Description  Возвращает требования к зачёту по семестрам и курсам
+// @Tags         Зачёт
+// @Produce      json
+// @Success      200  {object}  string
+// @Param term query string false "id семестра или all (по умолчанию текущий семестр)"
+// @Param limit query int false "Размер страницы"
+// @Param offset query int false "Смещение от начала списка"
+// @Param cursor query string false "Курсор следующей страницы из next_cursor"
+// @Param sort query string false "Сортировка, например term_refer,year"
+// @Router       /credit_requirements [get]
+func (a *Application) get_credit_requirements(c *gin.Context) {
+	term_id, ok := a.parseTermFilter(c, a.repo.GetCurrentTerm)
+	if !ok {
+		return
+	}
+
+	page, err := parsePageRequest(c, repository.CreditRequirementSortColumns)
+	if err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	requirements, info, err := a.repo.GetCreditRequirements(term_id, page)
+	if isPageError(err) {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, pageEnvelope(c, requirements, page, info))
+}
+
+// @Summary      Добавить требование к зачёту
+// @Description  Задаёт нужные часы и минимальную посещаемость для семестра и курса (Year = 0 - для всех курсов)
+// @Tags         Зачёт
+// @Accept       json
+// @Produce      json
+// @Success      201  {object}  ds.CreditRequirement
+// @Param requirement body ds.CreditRequirement true "Требование"
+// @Router       /credit_requirement/add [post]
+func (a *Application) add_credit_requirement(c *gin.Context) {
+	var requirement ds.CreditRequirement
+
+	if err := c.BindJSON(&requirement); err != nil {
+		c.String(http.StatusBadRequest, "Не получается распознать требование")
+		return
+	}
+
+	requirement.ID = 0
+
+	if _, err := a.repo.GetTermByID(int(requirement.TermRefer)); err != nil {
+		c.String(http.StatusNotFound, "Семестр не найден")
+		return
+	}
+
+	err := a.repo.CreateCreditRequirement(&requirement)
+	if errors.Is(err, repository.ErrInvalidCreditRequirement) {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err != nil {
+		c.String(http.StatusConflict, "Не получается создать требование\n"+err.Error())
+		return
+	}
+
+	c.JSON(http.StatusCreated, requirement)
+}
+
+// @Summary      Редактировать требование к зачёту
+// @Description  Заменяет требование целиком
+// @Tags         Зачёт
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  string
+// @Param requirement body ds.CreditRequirement true "Требование (должно содержать id)"
+// @Router       /credit_requirement/edit [put]
+func (a *Application) edit_credit_requirement(c *gin.Context) {
+	var requirement ds.CreditRequirement
+
+	if err := c.BindJSON(&requirement); err != nil || requirement.ID == 0 {
+		c.String(http.StatusBadRequest, "Не получается распознать требование")
+		return
+	}
+
+	err := a.repo.EditCreditRequirement(&requirement)
+	if errors.Is(err, repository.ErrInvalidCreditRequirement) {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.String(http.StatusNotFound, "Требование не найдено")
+		return
+	}
+
+	if err != nil {
+		c.String(http.StatusConflict, "Не получается изменить требование\n"+err.Error())
+		return
+	}
+
+	c.String(http.StatusOK, "Требование было успешно изменено")
+}
+
+// @Summary      Удалить требование к зачёту
+// @Description  Удаляет требование по id
+// @Tags         Зачёт
+// @Produce      json
+// @Success      200  {object}  string
+// @Param requirement_id path int true "id требования"
+// @Router       /credit_requirement/delete/{requirement_id} [delete]
+func (a *Application) delete_credit_requirement(c *gin.Context) {
+	requirement_id, err := strconv.Atoi(c.Param("requirement_id"))
+	if err != nil {
+		c.String(http.StatusBadRequest, "Не получается прочитать ID требования")
+		return
+	}
+
+	err = a.repo.DeleteCreditRequirement(requirement_id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.String(http.StatusOK, "Требование было успешно удалено")
+}
+
+// creditTerm читает семестр, за который считается зачёт. Зачёт всегда
+// считается за один семестр, поэтому term=all не подходит.
+func (a *Application) creditTerm(c *gin.Context) (uint, bool) {
+	term_id, ok := a.parseTermFilter(c, a.repo.GetCurrentTerm)
+	if !ok {
+		return 0, false
+	}
+
+	if term_id == 0 {
+		c.String(http.StatusBadRequest, "Нужно выбрать семестр")
+		return 0, false
+	}
+
+	return term_id, true
+}
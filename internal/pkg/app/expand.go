@@ -0,0 +1,36 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+
+	"sports_courses/internal/app/ds"
+
+	"github.com/gin-gonic/gin"
+)
+
+// parseEnrollmentExpand разбирает параметр expand. Без параметра, как и
+// раньше, вместе с записью возвращаются пользователь и модератор.
+func parseEnrollmentExpand(c *gin.Context) (ds.EnrollmentExpand, error) {
+	value, ok := c.GetQuery("expand")
+	if !ok {
+		return ds.EnrollmentExpand{User: true, Moderator: true}, nil
+	}
+
+	expand := ds.EnrollmentExpand{}
+	for _, name := range strings.Split(value, ",") {
+		switch strings.TrimSpace(name) {
+		case "":
+		case "groups":
+			expand.Groups = true
+		case "user":
+			expand.User = true
+		case "moderator":
+			expand.Moderator = true
+		default:
+			return ds.EnrollmentExpand{}, fmt.Errorf("неизвестное значение expand: %q", name)
+		}
+	}
+
+	return expand, nil
+}
@@ -0,0 +1,100 @@
+package app
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"time"
+
+	"sports_courses/internal/app/ds"
+	"sports_courses/internal/app/redis"
+)
+
+const defaultGroupsCacheTTL = 5 * time.Minute
+
+type groupsPage struct {
+	Groups []ds.Group
+	Info   ds.PageInfo
+	Facets ds.GroupFacets
+}
+
+func (a *Application) groupsCacheTTL() time.Duration {
+	if a.config.Redis.GroupsCacheTTL > 0 {
+		return a.config.Redis.GroupsCacheTTL
+	}
+
+	return defaultGroupsCacheTTL
+}
+
+// getGroupsCached возвращает страницу каталога вместе с фасетами через кэш в redis.
+func (a *Application) getGroupsCached(ctx context.Context, filter ds.GroupFilter, page ds.PageRequest) (groupsPage, error) {
+	load := func() (interface{}, error) {
+		groups, info, err := a.repo.GetGroups(filter, page)
+		if err != nil {
+			return nil, err
+		}
+
+		facets, err := a.repo.GetGroupFacets(filter)
+		if err != nil {
+			return nil, err
+		}
+
+		return groupsPage{Groups: groups, Info: info, Facets: facets}, nil
+	}
+
+	params, err := json.Marshal(struct {
+		Filter ds.GroupFilter
+		Page   ds.PageRequest
+	}{filter, page})
+	if err != nil {
+		return groupsPage{}, err
+	}
+
+	hash := sha1.Sum(params)
+
+	result := groupsPage{}
+	err = a.fetchGroupsCache(ctx, "list."+hex.EncodeToString(hash[:]), &result, load)
+
+	return result, err
+}
+
+func (a *Application) findGroupCached(ctx context.Context, title string) (ds.Group, error) {
+	load := func() (interface{}, error) {
+		return a.repo.FindGroup(ds.Group{Title: title})
+	}
+
+	result := ds.Group{}
+	err := a.fetchGroupsCache(ctx, "group."+title, &result, load)
+
+	return result, err
+}
+
+func (a *Application) fetchGroupsCache(ctx context.Context, key string, dest interface{}, load func() (interface{}, error)) error {
+	versionedKey, err := a.redis.VersionedKey(ctx, redis.GroupsCacheNamespace, key)
+	if err != nil {
+		log.Println("Не получается узнать версию кэша групп:", err)
+
+		value, err := load()
+		if err != nil {
+			return err
+		}
+
+		data, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+
+		return json.Unmarshal(data, dest)
+	}
+
+	return a.redis.Fetch(ctx, versionedKey, a.groupsCacheTTL(), dest, load)
+}
+
+// invalidateGroupsCache сбрасывает кэш каталога после любого изменения групп.
+func (a *Application) invalidateGroupsCache(ctx context.Context) {
+	if err := a.redis.InvalidateCache(ctx, redis.GroupsCacheNamespace); err != nil {
+		log.Println("Не получается сбросить кэш групп:", err)
+	}
+}
@@ -0,0 +1,201 @@
+package app
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"sports_courses/internal/app/ds"
+	"sports_courses/internal/app/repository"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// @Summary      Получить галерею группы
+// @Description  Возвращает картинки группы в порядке отображения
+// @Tags         Группы
+// @Produce      json
+// @Success      200  {array}  ds.GroupImage
+// @Param group_id path int true "id группы"
+// @Router       /group/images/{group_id} [get]
+func (a *Application) get_group_images(c *gin.Context) {
+	group_id, err := strconv.Atoi(c.Param("group_id"))
+	if err != nil {
+		c.String(http.StatusBadRequest, "Не получается прочитать ID группы")
+		return
+	}
+
+	images, err := a.repo.GetGroupImages(group_id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, images)
+}
+
+// @Summary      Добавить картинку в галерею группы
+// @Description  Загружает картинку в хранилище и добавляет её в конец галереи группы
+// @Tags         Группы
+// @Accept       multipart/form-data
+// @Produce      json
+// @Success      201  {object}  ds.GroupImage
+// @Param group_id path int true "id группы"
+// @Param file formData file true "Картинка"
+// @Param caption formData string false "Подпись"
+// @Param cover formData bool false "Сделать обложкой"
+// @Router       /group/add_gallery_image/{group_id} [post]
+func (a *Application) add_group_image(c *gin.Context) {
+	group_id, err := strconv.Atoi(c.Param("group_id"))
+	if err != nil {
+		c.String(http.StatusBadRequest, "Не получается прочитать ID группы")
+		return
+	}
+
+	group, err := a.repo.GetGroupByID(group_id)
+	if err != nil || group.ID == 0 {
+		c.String(http.StatusNotFound, "Группа не найдена")
+		return
+	}
+
+	objectName, err := uploadFormFile(c, groupImagesBucket)
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Не получилось загрузить картинку в minio")
+		log.Println("Не получилось загрузить картинку в minio:", err)
+		return
+	}
+
+	image := &ds.GroupImage{
+		GroupRefer: group_id,
+		ImageName:  objectName,
+		Caption:    c.PostForm("caption"),
+		IsCover:    c.PostForm("cover") == "true",
+	}
+
+	err = a.repo.AddGroupImage(image)
+	if err != nil {
+		removeObjects(c.Request.Context(), groupImagesBucket, objectName)
+		c.String(http.StatusInternalServerError, "Не получается добавить картинку в галерею")
+		log.Println(err)
+		return
+	}
+
+	a.invalidateGroupsCache(c.Request.Context())
+
+	c.JSON(http.StatusCreated, image)
+}
+
+// @Summary      Изменить подпись картинки
+// @Description  Обновляет подпись картинки из галереи группы
+// @Tags         Группы
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  string
+// @Param request_body body ds.EditGroupImageRequestBody true "Параметры запроса"
+// @Router       /group/image/edit [put]
+func (a *Application) edit_group_image(c *gin.Context) {
+	var requestBody ds.EditGroupImageRequestBody
+
+	if err := c.BindJSON(&requestBody); err != nil {
+		c.String(http.StatusBadRequest, "Передан плохой json")
+		return
+	}
+
+	err := a.repo.EditGroupImageCaption(requestBody.ImageID, requestBody.Caption)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.String(http.StatusNotFound, "Картинка не найдена")
+		return
+	}
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.String(http.StatusOK, "Подпись картинки обновлена")
+}
+
+// @Summary      Изменить порядок картинок
+// @Description  Принимает id всех картинок галереи группы в новом порядке
+// @Tags         Группы
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  string
+// @Param request_body body ds.ReorderGroupImagesRequestBody true "Параметры запроса"
+// @Router       /group/images/reorder [put]
+func (a *Application) reorder_group_images(c *gin.Context) {
+	var requestBody ds.ReorderGroupImagesRequestBody
+
+	if err := c.BindJSON(&requestBody); err != nil {
+		c.String(http.StatusBadRequest, "Передан плохой json")
+		return
+	}
+
+	err := a.repo.ReorderGroupImages(requestBody.GroupID, requestBody.ImageIDs)
+	if errors.Is(err, repository.ErrImagesMismatch) {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.String(http.StatusOK, "Порядок картинок обновлён")
+}
+
+// @Summary      Сделать картинку обложкой
+// @Description  Назначает картинку из галереи обложкой группы
+// @Tags         Группы
+// @Produce      json
+// @Success      200  {object}  string
+// @Param image_id path int true "id картинки"
+// @Router       /group/image/set_cover/{image_id} [put]
+func (a *Application) set_group_cover_image(c *gin.Context) {
+	image_id, err := strconv.Atoi(c.Param("image_id"))
+	if err != nil {
+		c.String(http.StatusBadRequest, "Не получается прочитать ID картинки")
+		return
+	}
+
+	err = a.repo.SetGroupCoverImage(image_id)
+	if err != nil {
+		c.String(http.StatusNotFound, "Не получается назначить обложку")
+		log.Println(err)
+		return
+	}
+
+	a.invalidateGroupsCache(c.Request.Context())
+
+	c.String(http.StatusOK, "Обложка группы обновлена")
+}
+
+// @Summary      Удалить картинку
+// @Description  Удаляет картинку из галереи группы и из хранилища
+// @Tags         Группы
+// @Produce      json
+// @Success      200  {object}  string
+// @Param image_id path int true "id картинки"
+// @Router       /group/image/delete/{image_id} [delete]
+func (a *Application) delete_group_image(c *gin.Context) {
+	image_id, err := strconv.Atoi(c.Param("image_id"))
+	if err != nil {
+		c.String(http.StatusBadRequest, "Не получается прочитать ID картинки")
+		return
+	}
+
+	image, err := a.repo.DeleteGroupImage(image_id)
+	if err != nil {
+		c.String(http.StatusNotFound, "Не получается удалить картинку")
+		log.Println(err)
+		return
+	}
+
+	a.removeGroupImageObjects(c.Request.Context(), image.ImageName)
+	a.invalidateGroupsCache(c.Request.Context())
+
+	c.String(http.StatusOK, "Картинка удалена")
+}
@@ -0,0 +1,235 @@
+package app
+
+import (
+	"errors"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"sports_courses/internal/app/ds"
+	"sports_courses/internal/app/repository"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+var sessionTimePattern = regexp.MustCompile(`^([01]\d|2[0-3]):[0-5]\d$`)
+
+// @Summary      Получить места
+// @Description  Возвращает список мест проведения занятий
+// @Tags         Места
+// @Produce      json
+// @Success      200  {object}  string
+// @Param limit query int false "Размер страницы"
+// @Param offset query int false "Смещение от начала списка"
+// @Param cursor query string false "Курсор следующей страницы из next_cursor"
+// @Param sort query string false "Сортировка, например building,room"
+// @Router       /locations [get]
+func (a *Application) get_locations(c *gin.Context) {
+	page, err := parsePageRequest(c, repository.LocationSortColumns)
+	if err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	locations, info, err := a.repo.GetLocations(page)
+	if isPageError(err) {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, pageEnvelope(c, locations, page, info))
+}
+
+// @Summary      Получить место
+// @Description  Возвращает место проведения занятий по его id
+// @Tags         Места
+// @Produce      json
+// @Success      200  {object}  ds.Location
+// @Param location_id path int true "id места"
+// @Router       /location/{location_id} [get]
+func (a *Application) get_location(c *gin.Context) {
+	location_id, err := strconv.Atoi(c.Param("location_id"))
+	if err != nil {
+		c.String(http.StatusBadRequest, "Не получается прочитать ID места")
+		return
+	}
+
+	location, err := a.repo.GetLocationByID(location_id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.String(http.StatusNotFound, "Место не найдено")
+		return
+	}
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, location)
+}
+
+// @Summary      Занятость места
+// @Description  Возвращает занятия действующих групп в месте по дням недели
+// @Tags         Места
+// @Produce      json
+// @Success      200  {array}  ds.LocationOccupancyDay
+// @Param location_id path int true "id места"
+// @Param term query string false "id семестра или all (по умолчанию текущий семестр)"
+// @Router       /locations/{location_id}/occupancy [get]
+func (a *Application) get_location_occupancy(c *gin.Context) {
+	location_id, err := strconv.Atoi(c.Param("location_id"))
+	if err != nil {
+		c.String(http.StatusBadRequest, "Не получается прочитать ID места")
+		return
+	}
+
+	if _, err := a.repo.GetLocationByID(location_id); err != nil {
+		c.String(http.StatusNotFound, "Место не найдено")
+		return
+	}
+
+	term_id, ok := a.parseTermFilter(c, a.repo.GetCurrentTerm)
+	if !ok {
+		return
+	}
+
+	days, err := a.repo.GetLocationOccupancy(location_id, term_id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, days)
+}
+
+// @Summary      Добавить место
+// @Description  Создаёт новое место проведения занятий
+// @Tags         Места
+// @Accept       json
+// @Produce      json
+// @Success      201  {object}  ds.Location
+// @Param location body ds.Location true "Место"
+// @Router       /location/add [post]
+func (a *Application) add_location(c *gin.Context) {
+	var location ds.Location
+
+	if err := c.BindJSON(&location); err != nil {
+		c.String(http.StatusBadRequest, "Не получается распознать место")
+		return
+	}
+
+	location.ID = 0
+	location.Building = strings.TrimSpace(location.Building)
+	location.Room = strings.TrimSpace(location.Room)
+	if location.Building == "" {
+		c.String(http.StatusBadRequest, "Корпус не может быть пустым")
+		return
+	}
+
+	err := a.repo.CreateLocation(&location)
+	if err != nil {
+		c.String(http.StatusConflict, "Не получается создать место\n"+err.Error())
+		return
+	}
+
+	c.JSON(http.StatusCreated, location)
+}
+
+// @Summary      Редактировать место
+// @Description  Находит место по id и обновляет переданные поля
+// @Tags         Места
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  string
+// @Param location body ds.Location true "Место (должно содержать id)"
+// @Router       /location/edit [put]
+func (a *Application) edit_location(c *gin.Context) {
+	var location ds.Location
+
+	if err := c.BindJSON(&location); err != nil || location.ID == 0 {
+		c.String(http.StatusBadRequest, "Не получается распознать место")
+		return
+	}
+
+	location.Building = strings.TrimSpace(location.Building)
+	location.Room = strings.TrimSpace(location.Room)
+
+	err := a.repo.EditLocation(&location)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	a.invalidateGroupsCache(c.Request.Context())
+
+	c.String(http.StatusOK, "Место было успешно изменено")
+}
+
+// @Summary      Удалить место
+// @Description  Удаляет место, в котором не проходят занятия ни одной группы
+// @Tags         Места
+// @Produce      json
+// @Success      200  {object}  string
+// @Param location_id path int true "id места"
+// @Router       /location/delete/{location_id} [delete]
+func (a *Application) delete_location(c *gin.Context) {
+	location_id, err := strconv.Atoi(c.Param("location_id"))
+	if err != nil {
+		c.String(http.StatusBadRequest, "Не получается прочитать ID места")
+		return
+	}
+
+	err = a.repo.DeleteLocation(location_id)
+	if errors.Is(err, repository.ErrLocationInUse) {
+		c.String(http.StatusConflict, err.Error())
+		return
+	}
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.String(http.StatusOK, "Место было успешно удалено")
+}
+
+// validateGroupSessions проверяет формат занятий группы до обращения к базе.
+func validateGroupSessions(sessions []ds.GroupSession) error {
+	for _, session := range sessions {
+		if session.Weekday < 1 || session.Weekday > 7 {
+			return errors.New("день недели занятия должен быть от 1 до 7")
+		}
+
+		if !sessionTimePattern.MatchString(session.StartTime) || !sessionTimePattern.MatchString(session.EndTime) {
+			return errors.New("время занятия должно быть в формате ЧЧ:ММ")
+		}
+
+		if session.StartTime >= session.EndTime {
+			return errors.New("занятие должно заканчиваться позже, чем начинается")
+		}
+	}
+
+	return nil
+}
+
+// writeGroupSaveError отвечает на ошибку сохранения группы. Конфликт
+// расписания возвращается списком занятых мест, чтобы его можно было показать.
+func writeGroupSaveError(c *gin.Context, err error, status int, message string) {
+	var conflictErr *repository.LocationConflictError
+	if errors.As(err, &conflictErr) {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":     conflictErr.Error(),
+			"conflicts": conflictErr.Conflicts,
+		})
+		return
+	}
+
+	c.String(status, message+"\n"+err.Error())
+}
@@ -0,0 +1,281 @@
+package app
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"sports_courses/internal/app/ds"
+	"sports_courses/internal/app/repository"
+	"sports_courses/internal/app/role"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/minio/minio-go/v7"
+	"gorm.io/gorm"
+)
+
+const (
+	medicalCertificatesBucket = "medicalcertificates"
+	maxCertificateSize        = 10 << 20
+)
+
+var certificateExtensions = []string{".pdf", ".jpg", ".jpeg", ".png"}
+
+// @Summary      Загрузить медицинскую справку
+// @Description  Студент загружает скан справки, она попадает в очередь на проверку модератору
+// @Tags         Медицинские справки
+// @Accept       multipart/form-data
+// @Produce      json
+// @Success      201  {object}  ds.MedicalCertificate
+// @Param file formData file true "Скан справки (pdf, jpg или png)"
+// @Router       /medical_certificate/upload [post]
+func (a *Application) upload_medical_certificate(c *gin.Context) {
+	_, header, err := c.Request.FormFile("file")
+	if err != nil {
+		c.String(http.StatusBadRequest, "Нужно передать файл справки")
+		return
+	}
+
+	extension := strings.ToLower(filepath.Ext(header.Filename))
+	if !slices.Contains(certificateExtensions, extension) || header.Size > maxCertificateSize {
+		c.String(http.StatusBadRequest, "Справка должна быть файлом pdf, jpg или png не больше 10 МБ")
+		return
+	}
+
+	_userUUID, _ := c.Get("userUUID")
+	userUUID := _userUUID.(uuid.UUID)
+
+	objectName, err := uploadFormFile(c, medicalCertificatesBucket)
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Не получилось загрузить справку в minio")
+		log.Println("Не получилось загрузить справку в minio:", err)
+		return
+	}
+
+	certificate := &ds.MedicalCertificate{
+		UserRefer:    userUUID,
+		FileName:     objectName,
+		Status:       ds.CertificatePending,
+		DateUploaded: time.Now(),
+	}
+
+	err = a.repo.CreateMedicalCertificate(certificate)
+	if err != nil {
+		removeObjects(c.Request.Context(), medicalCertificatesBucket, objectName)
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, certificate)
+}
+
+// @Summary      Мои медицинские справки
+// @Description  Возвращает справки текущего пользователя, сначала новые
+// @Tags         Медицинские справки
+// @Produce      json
+// @Success      200  {array}  ds.MedicalCertificate
+// @Router       /medical_certificates/my [get]
+func (a *Application) get_my_medical_certificates(c *gin.Context) {
+	_userUUID, _ := c.Get("userUUID")
+	userUUID := _userUUID.(uuid.UUID)
+
+	certificates, err := a.repo.GetUserMedicalCertificates(userUUID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, certificates)
+}
+
+// @Summary      Файл медицинской справки
+// @Description  Отдаёт файл справки её владельцу или модератору
+// @Tags         Медицинские справки
+// @Produce      octet-stream
+// @Success      200  {file}  file
+// @Param certificate_id path int true "id справки"
+// @Router       /medical_certificate/file/{certificate_id} [get]
+func (a *Application) get_medical_certificate_file(c *gin.Context) {
+	certificate_id, err := strconv.Atoi(c.Param("certificate_id"))
+	if err != nil {
+		c.String(http.StatusBadRequest, "Не получается прочитать ID справки")
+		return
+	}
+
+	certificate, err := a.repo.GetMedicalCertificateByID(certificate_id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.String(http.StatusNotFound, "Справка не найдена")
+		return
+	}
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	_roleNumber, _ := c.Get("role")
+	_userUUID, _ := c.Get("userUUID")
+	roleNumber := _roleNumber.(role.Role)
+	userUUID := _userUUID.(uuid.UUID)
+
+	if certificate.UserRefer != userUUID && roleNumber != role.Moderator && roleNumber != role.Admin {
+		c.String(http.StatusForbidden, "Это чужая справка")
+		return
+	}
+
+	minioClient, err := newMinioClient()
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	object, err := minioClient.GetObject(c.Request.Context(), medicalCertificatesBucket, certificate.FileName, minio.GetObjectOptions{})
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	defer object.Close()
+
+	info, err := object.Stat()
+	if err != nil {
+		c.String(http.StatusNotFound, "Файл справки не найден")
+		return
+	}
+
+	c.DataFromReader(http.StatusOK, info.Size, info.ContentType, object, map[string]string{
+		"Cache-Control": "private, no-store",
+	})
+}
+
+// @Summary      Очередь медицинских справок
+// @Description  Возвращает справки с переданным статусом (по умолчанию "На проверке") вместе со студентами, сначала старые
+// @Tags         Медицинские справки
+// @Produce      json
+// @Success      200  {object}  string
+// @Param status query string false "Статус справки"
+// @Param limit query int false "Размер страницы"
+// @Param offset query int false "Смещение от начала списка"
+// @Param cursor query string false "Курсор следующей страницы из next_cursor"
+// @Param sort query string false "Сортировка, например date_uploaded"
+// @Router       /medical_certificates [get]
+func (a *Application) get_medical_certificates(c *gin.Context) {
+	status := c.DefaultQuery("status", ds.CertificatePending)
+
+	page, err := parsePageRequest(c, repository.CertificateSortColumns)
+	if err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	certificates, info, err := a.repo.GetMedicalCertificates(status, page)
+	if isPageError(err) {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, pageEnvelope(c, certificates, page, info))
+}
+
+// @Summary      Проверить медицинскую справку
+// @Description  Модератор одобряет справку со сроком действия или отклоняет её с комментарием
+// @Tags         Медицинские справки
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  string
+// @Param request_body body ds.ReviewCertificateRequestBody true "Решение по справке"
+// @Router       /medical_certificate/review [put]
+func (a *Application) review_medical_certificate(c *gin.Context) {
+	var requestBody ds.ReviewCertificateRequestBody
+
+	if err := c.BindJSON(&requestBody); err != nil {
+		c.String(http.StatusBadRequest, "Передан плохой json")
+		return
+	}
+
+	if requestBody.Approve && !requestBody.ExpiresAt.After(time.Now()) {
+		c.String(http.StatusBadRequest, "У одобренной справки должна быть дата окончания в будущем")
+		return
+	}
+
+	_userUUID, _ := c.Get("userUUID")
+	userUUID := _userUUID.(uuid.UUID)
+
+	err := a.repo.ReviewMedicalCertificate(requestBody.CertificateID, userUUID, requestBody.Approve, requestBody.ExpiresAt, requestBody.Comment)
+	if errors.Is(err, repository.ErrCertificateReviewed) {
+		c.String(http.StatusConflict, err.Error())
+		return
+	}
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.String(http.StatusNotFound, "Справка не найдена")
+		return
+	}
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.String(http.StatusOK, "Справка проверена")
+}
+
+// checkMedicalClearance не даёт сформировать запись в группы, требующие
+// справку, если у студента нет действующей одобренной справки.
+func (a *Application) checkMedicalClearance(c *gin.Context, enrollment_id int, userUUID uuid.UUID) bool {
+	titles, err := a.repo.GetClearanceGroups(enrollment_id)
+	if err != nil {
+		c.Error(err)
+		return false
+	}
+
+	if len(titles) == 0 {
+		return true
+	}
+
+	valid, err := a.repo.HasValidMedicalCertificate(userUUID, time.Now())
+	if err != nil {
+		c.Error(err)
+		return false
+	}
+
+	if !valid {
+		c.String(http.StatusForbidden, "Для записи в группы "+strings.Join(titles, ", ")+" нужна действующая медицинская справка")
+		return false
+	}
+
+	return true
+}
+
+// checkFormingEnrollment проверяет правила записи и справку владельца записи
+// перед тем, как запись станет сформированной.
+func (a *Application) checkFormingEnrollment(c *gin.Context, enrollment_id int) bool {
+	enrollment, err := a.repo.FindEnrollment(&ds.Enrollment{ID: uint(enrollment_id)}, ds.EnrollmentExpand{})
+	if err != nil {
+		c.Error(err)
+		return false
+	}
+
+	if enrollment.ID == 0 || enrollment.UserRefer == nil {
+		c.String(http.StatusNotFound, "Запись не найдена")
+		return false
+	}
+
+	group_ids, err := a.enrollmentGroupIDs(enrollment_id)
+	if err != nil {
+		c.Error(err)
+		return false
+	}
+
+	return a.checkEnrollmentRules(c, enrollment_id, group_ids) && a.checkMedicalClearance(c, enrollment_id, *enrollment.UserRefer)
+}
@@ -3,6 +3,7 @@ package app
 import (
 	"log"
 	"net/http"
+	"sports_courses/internal/app/config"
 	"sports_courses/internal/app/ds"
 	"sports_courses/internal/app/role"
 	"strings"
@@ -42,11 +43,34 @@ func (a *Application) WithAuthCheck(assignedRoles ...role.Role) func(context *gi
 			jwtStr = jwtStr[len(jwtPrefix):]
 		}
 
-		err := a.redis.CheckJWTInBlackList(c.Request.Context(), jwtStr)
+		if jwtStr != "" {
+			blacklisted, err := a.redis.IsJWTBlacklisted(c.Request.Context(), jwtStr)
+			if err != nil {
+				log.Println("Не получается проверить токен в блэклисте:", err)
 
-		if err == nil && !isPassing { // значит что токен в блеклисте
-			c.AbortWithStatus(http.StatusForbidden)
-			return
+				// отзывы токенов дублируются в базе
+				blacklisted, err = a.repo.IsTokenRevoked(jwtStr)
+			}
+
+			if err != nil {
+				log.Println("Не получается проверить отзыв токена в базе:", err)
+
+				if a.config.Redis.BlacklistFailPolicy == config.BlacklistFailClosed {
+					if !isPassing {
+						c.AbortWithStatus(http.StatusServiceUnavailable)
+					}
+					// без блэклиста токену нельзя доверять - пускаем как гостя
+					return
+				}
+			}
+
+			if blacklisted {
+				if !isPassing {
+					c.AbortWithStatus(http.StatusForbidden)
+				}
+				// отозванный токен на открытых маршрутах - это гость
+				return
+			}
 		}
 
 		token, err := jwt.ParseWithClaims(jwtStr, &ds.JWTClaims{}, func(token *jwt.Token) (interface{}, error) {
@@ -0,0 +1,85 @@
+package app
+
+import (
+	"context"
+	"log"
+	"path/filepath"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+const groupImagesBucket = "groupimages"
+
+func newMinioClient() (*minio.Client, error) {
+	return minio.New("127.0.0.1:9000", &minio.Options{
+		Creds:  credentials.NewStaticV4("minioadmin", "minioadmin", ""),
+		Secure: false,
+	})
+}
+
+// uploadFormFile загружает файл из поля "file" multipart-формы в bucket и
+// возвращает имя созданного объекта. Имя генерируется, чтобы файлы с
+// одинаковыми названиями от разных групп не перезаписывали друг друга.
+func uploadFormFile(c *gin.Context, bucket string) (string, error) {
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	minioClient, err := newMinioClient()
+	if err != nil {
+		return "", err
+	}
+
+	objectName := uuid.New().String() + filepath.Ext(header.Filename)
+	_, err = minioClient.PutObject(c.Request.Context(), bucket, objectName, file, header.Size, minio.PutObjectOptions{
+		ContentType: header.Header.Get("Content-Type"),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return objectName, nil
+}
+
+// removeObjects удаляет объекты из bucket. Ошибки только логируются: запись в
+// БД к этому моменту уже удалена, и висящий объект не должен ломать запрос.
+func removeObjects(ctx context.Context, bucket string, objectNames ...string) {
+	if len(objectNames) == 0 {
+		return
+	}
+
+	minioClient, err := newMinioClient()
+	if err != nil {
+		log.Println("Не получается подключиться к minio:", err)
+		return
+	}
+
+	for _, objectName := range objectNames {
+		if objectName == "" {
+			continue
+		}
+
+		err := minioClient.RemoveObject(ctx, bucket, objectName, minio.RemoveObjectOptions{})
+		if err != nil {
+			log.Printf("Не получилось удалить объект %s из minio: %v", objectName, err)
+		}
+	}
+}
+
+// removeGroupImageObjects удаляет объекты картинок групп, на которые больше
+// не ссылается ни одна группа: копии групп в новом семестре делят объекты с
+// исходными.
+func (a *Application) removeGroupImageObjects(ctx context.Context, objectNames ...string) {
+	unused, err := a.repo.UnusedGroupImageNames(objectNames)
+	if err != nil {
+		log.Println("Не получается проверить, используются ли картинки:", err)
+		return
+	}
+
+	removeObjects(ctx, groupImagesBucket, unused...)
+}
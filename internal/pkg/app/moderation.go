@@ -0,0 +1,259 @@
+package app
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"sports_courses/internal/app/ds"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	moderationPingInterval = 20 * time.Second
+	moderationWriteTimeout = 10 * time.Second
+	// moderationPresenceTTL - через сколько без пинга подключение считается пропавшим
+	moderationPresenceTTL = 3 * moderationPingInterval
+	moderationSendBuffer  = 64
+)
+
+var moderationUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+}
+
+// moderationHub раздаёт события очереди модераторам, подключённым к этому
+// экземпляру сервиса.
+type moderationHub struct {
+	mu    sync.Mutex
+	conns map[chan ds.ModerationEvent]struct{}
+}
+
+func newModerationHub() *moderationHub {
+	return &moderationHub{conns: map[chan ds.ModerationEvent]struct{}{}}
+}
+
+func (h *moderationHub) add() chan ds.ModerationEvent {
+	send := make(chan ds.ModerationEvent, moderationSendBuffer)
+
+	h.mu.Lock()
+	h.conns[send] = struct{}{}
+	h.mu.Unlock()
+
+	return send
+}
+
+func (h *moderationHub) remove(send chan ds.ModerationEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.conns[send]; ok {
+		delete(h.conns, send)
+		close(send)
+	}
+}
+
+// broadcast отправляет событие всем модераторам. Модератор, который не
+// успевает читать, отключается и при переподключении получит очередь заново.
+func (h *moderationHub) broadcast(event ds.ModerationEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for send := range h.conns {
+		select {
+		case send <- event:
+		default:
+			delete(h.conns, send)
+			close(send)
+		}
+	}
+}
+
+// startModerationQueue подписывает хаб на события очереди из redis.
+func (a *Application) startModerationQueue(ctx context.Context) {
+	go func() {
+		for ctx.Err() == nil {
+			err := a.redis.SubscribeModerationEvents(ctx, a.moderation.broadcast)
+			if err != nil && ctx.Err() == nil {
+				log.Println("Подписка на очередь модерации прервалась:", err)
+			}
+			time.Sleep(streamRetryInterval)
+		}
+	}()
+}
+
+// publishModerationEvent сообщает модераторам об изменении очереди.
+func (a *Application) publishModerationEvent(ctx context.Context, event ds.ModerationEvent) {
+	if err := a.redis.PublishModerationEvent(ctx, event); err != nil {
+		log.Println("Не получается отправить событие очереди модерации:", err)
+	}
+}
+
+// publishEnrollmentStatus сообщает модераторам, что запись попала в очередь
+// (стала сформированной) или ушла из неё.
+func (a *Application) publishEnrollmentStatus(ctx context.Context, enrollment_id uint, status string, moderator *uuid.UUID) {
+	event := ds.ModerationEvent{
+		Type:         ds.QueueEnrollmentProcessed,
+		EnrollmentID: enrollment_id,
+		Status:       status,
+		Moderator:    moderator,
+	}
+
+	if status == "Сформирован" {
+		event.Type = ds.QueueEnrollmentAdded
+	}
+
+	a.publishModerationEvent(ctx, event)
+}
+
+// publishModeratorPresence рассылает модераторам, кто сейчас какую запись смотрит.
+func (a *Application) publishModeratorPresence(ctx context.Context) {
+	presence, err := a.redis.GetModeratorPresence(ctx, moderationPresenceTTL)
+	if err != nil {
+		log.Println("Не получается узнать, кто смотрит очередь модерации:", err)
+		return
+	}
+
+	a.publishModerationEvent(ctx, ds.ModerationEvent{Type: ds.QueuePresence, Presence: presence})
+}
+
+// @Summary      Очередь модерации
+// @Description  WebSocket: при подключении присылает очередь сформированных записей и список модераторов, затем - новые и обработанные записи и кто какую запись смотрит. Модератор сообщает {"Type":"view","EnrollmentID":1}, открыв запись, и {"Type":"leave"}, закрыв её
+// @Tags         Модерация
+// @Success      101  {object}  ds.ModerationEvent
+// @Router       /moderation/queue [get]
+func (a *Application) moderation_queue(c *gin.Context) {
+	if !a.redis.Available() {
+		c.String(http.StatusServiceUnavailable, "Очередь модерации временно недоступна")
+		return
+	}
+
+	_userUUID, _ := c.Get("userUUID")
+	userUUID := _userUUID.(uuid.UUID)
+
+	moderator, err := a.repo.GetUserByID(userUUID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	// подписываемся до чтения очереди, чтобы не потерять записи, изменившиеся
+	// между чтением и подпиской; такие события придут сразу после снимка
+	send := a.moderation.add()
+	defer a.moderation.remove(send)
+
+	queue, err := a.repo.GetModerationQueue()
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	conn, err := moderationUpgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		log.Println("Не получается открыть WebSocket очереди модерации:", err)
+		return
+	}
+	defer conn.Close()
+
+	// контекст запроса после перехода на WebSocket не отменяется, поэтому
+	// подключение живёт со своим
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	presence := ds.ModeratorPresence{
+		ConnectionID:  uuid.New().String(),
+		ModeratorUUID: userUUID,
+		Name:          moderator.Name,
+		SeenAt:        time.Now(),
+	}
+
+	if err := a.redis.SetModeratorPresence(ctx, presence); err != nil {
+		log.Println("Не получается отметить модератора в очереди:", err)
+	}
+	defer func() {
+		if err := a.redis.RemoveModeratorPresence(context.Background(), presence.ConnectionID); err != nil {
+			log.Println("Не получается убрать модератора из очереди:", err)
+		}
+		a.publishModeratorPresence(context.Background())
+	}()
+
+	snapshot := ds.ModerationEvent{Type: ds.QueueSnapshot, Enrollments: queue}
+	snapshot.Presence, _ = a.redis.GetModeratorPresence(ctx, moderationPresenceTTL)
+	if err := writeModerationEvent(conn, snapshot); err != nil {
+		return
+	}
+
+	a.publishModeratorPresence(ctx)
+
+	views := make(chan uint)
+	go func() {
+		defer cancel()
+
+		for {
+			message := ds.ModerationMessage{}
+			if err := conn.ReadJSON(&message); err != nil {
+				return
+			}
+
+			enrollment_id := uint(0)
+			switch message.Type {
+			case ds.QueueMessageView:
+				enrollment_id = message.EnrollmentID
+			case ds.QueueMessageLeave:
+			default:
+				continue
+			}
+
+			select {
+			case views <- enrollment_id:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	ping := time.NewTicker(moderationPingInterval)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case enrollment_id := <-views:
+			presence.EnrollmentID = enrollment_id
+			presence.SeenAt = time.Now()
+			if err := a.redis.SetModeratorPresence(ctx, presence); err != nil {
+				log.Println("Не получается отметить модератора в очереди:", err)
+			}
+			a.publishModeratorPresence(ctx)
+		case event, ok := <-send:
+			if !ok {
+				return
+			}
+
+			if err := writeModerationEvent(conn, event); err != nil {
+				return
+			}
+		case <-ping.C:
+			presence.SeenAt = time.Now()
+			if err := a.redis.SetModeratorPresence(ctx, presence); err != nil {
+				log.Println("Не получается отметить модератора в очереди:", err)
+			}
+
+			conn.SetWriteDeadline(time.Now().Add(moderationWriteTimeout))
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func writeModerationEvent(conn *websocket.Conn, event ds.ModerationEvent) error {
+	conn.SetWriteDeadline(time.Now().Add(moderationWriteTimeout))
+	return conn.WriteJSON(event)
+}
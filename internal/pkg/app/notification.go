@@ -0,0 +1,172 @@
+package app
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"sports_courses/internal/app/ds"
+	"sports_courses/internal/app/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// @Summary      Получить уведомления
+// @Description  Возвращает уведомления текущего пользователя, сначала новые, и число непрочитанных
+// @Tags         Уведомления
+// @Produce      json
+// @Success      200  {object}  string
+// @Param unread query bool false "Только непрочитанные"
+// @Param limit query int false "Размер страницы"
+// @Param offset query int false "Смещение от начала списка"
+// @Param cursor query string false "Курсор следующей страницы из next_cursor"
+// @Param sort query string false "Сортировка, например -created_at"
+// @Router       /notifications [get]
+func (a *Application) get_notifications(c *gin.Context) {
+	page, err := parsePageRequest(c, repository.NotificationSortColumns)
+	if err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	unread := false
+	if param := c.Query("unread"); param != "" {
+		unread, err = strconv.ParseBool(param)
+		if err != nil {
+			c.String(http.StatusBadRequest, "Параметр unread должен быть true или false")
+			return
+		}
+	}
+
+	_userUUID, _ := c.Get("userUUID")
+	userUUID := _userUUID.(uuid.UUID)
+
+	notifications, info, err := a.repo.GetNotifications(userUUID, unread, page)
+	if isPageError(err) {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	count, err := a.repo.CountUnreadNotifications(userUUID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	envelope := pageEnvelope(c, notifications, page, info)
+	envelope["unread"] = count
+
+	c.JSON(http.StatusOK, envelope)
+}
+
+// @Summary      Прочитать уведомление
+// @Description  Отмечает уведомление прочитанным
+// @Tags         Уведомления
+// @Produce      json
+// @Success      200  {object}  string
+// @Param notification_id path int true "id уведомления"
+// @Router       /notification/read/{notification_id} [put]
+func (a *Application) mark_notification_read(c *gin.Context) {
+	notification_id, err := strconv.Atoi(c.Param("notification_id"))
+	if err != nil {
+		c.String(http.StatusBadRequest, "Не получается прочитать ID уведомления")
+		return
+	}
+
+	_userUUID, _ := c.Get("userUUID")
+	userUUID := _userUUID.(uuid.UUID)
+
+	err = a.repo.MarkNotificationRead(userUUID, notification_id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.String(http.StatusNotFound, "Уведомление не найдено")
+		return
+	}
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.String(http.StatusOK, "Уведомление прочитано")
+}
+
+// @Summary      Прочитать все уведомления
+// @Description  Отмечает прочитанными все уведомления текущего пользователя
+// @Tags         Уведомления
+// @Produce      json
+// @Success      200  {object}  string
+// @Router       /notifications/read_all [put]
+func (a *Application) mark_all_notifications_read(c *gin.Context) {
+	_userUUID, _ := c.Get("userUUID")
+	userUUID := _userUUID.(uuid.UUID)
+
+	count, err := a.repo.MarkAllNotificationsRead(userUUID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.String(http.StatusOK, "Прочитано уведомлений: "+strconv.FormatInt(count, 10))
+}
+
+// @Summary      Настройки уведомлений
+// @Description  Возвращает, какие типы уведомлений включены у текущего пользователя
+// @Tags         Уведомления
+// @Produce      json
+// @Success      200  {array}  ds.NotificationPreference
+// @Router       /notifications/preferences [get]
+func (a *Application) get_notification_preferences(c *gin.Context) {
+	_userUUID, _ := c.Get("userUUID")
+	userUUID := _userUUID.(uuid.UUID)
+
+	preferences, err := a.repo.GetNotificationPreferences(userUUID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, preferences)
+}
+
+// @Summary      Изменить настройки уведомлений
+// @Description  Включает или выключает уведомления переданных типов
+// @Tags         Уведомления
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  string
+// @Param request_body body []ds.NotificationPreferenceRequestBody true "Настройки"
+// @Router       /notifications/preferences [put]
+func (a *Application) edit_notification_preferences(c *gin.Context) {
+	var requestBody []ds.NotificationPreferenceRequestBody
+
+	if err := c.BindJSON(&requestBody); err != nil {
+		c.String(http.StatusBadRequest, "Не получается распознать json запрос")
+		return
+	}
+
+	for _, preference := range requestBody {
+		if !slices.Contains(ds.EventTypes, preference.Type) {
+			c.String(http.StatusBadRequest, "Неизвестный тип уведомлений \""+preference.Type+"\"")
+			return
+		}
+	}
+
+	_userUUID, _ := c.Get("userUUID")
+	userUUID := _userUUID.(uuid.UUID)
+
+	err := a.repo.SetNotificationPreferences(userUUID, requestBody)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.String(http.StatusOK, "Настройки уведомлений сохранены")
+}
@@ -0,0 +1,132 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"sports_courses/internal/app/ds"
+	"sports_courses/internal/app/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultPageLimit = 50
+	maxPageLimit     = 500
+)
+
+// parsePageRequest разбирает параметры limit, offset, cursor и sort. В sort
+// через запятую перечисляются колонки из sortable, "-" перед колонкой
+// означает сортировку по убыванию: sort=-date_created,title.
+func parsePageRequest(c *gin.Context, sortable []string) (ds.PageRequest, error) {
+	page := ds.PageRequest{
+		Limit:  defaultPageLimit,
+		Cursor: c.Query("cursor"),
+	}
+
+	if limit := c.Query("limit"); limit != "" {
+		value, err := strconv.Atoi(limit)
+		if err != nil || value < 1 || value > maxPageLimit {
+			return ds.PageRequest{}, fmt.Errorf("limit должен быть числом от 1 до %d", maxPageLimit)
+		}
+		page.Limit = value
+	}
+
+	if offset := c.Query("offset"); offset != "" {
+		value, err := strconv.Atoi(offset)
+		if err != nil || value < 0 {
+			return ds.PageRequest{}, errors.New("offset должен быть неотрицательным числом")
+		}
+		page.Offset = value
+	}
+
+	if page.Cursor != "" && page.Offset != 0 {
+		return ds.PageRequest{}, errors.New("нельзя одновременно передавать cursor и offset")
+	}
+
+	if sort := c.Query("sort"); sort != "" {
+		seen := map[string]bool{}
+
+		for _, column := range strings.Split(sort, ",") {
+			field := ds.SortField{Column: strings.TrimSpace(column)}
+			if strings.HasPrefix(field.Column, "-") {
+				field.Desc = true
+				field.Column = field.Column[1:]
+			}
+
+			if !slices.Contains(sortable, field.Column) {
+				return ds.PageRequest{}, fmt.Errorf("сортировка по %q не поддерживается, доступны: %s", field.Column, strings.Join(sortable, ", "))
+			}
+
+			if seen[field.Column] {
+				return ds.PageRequest{}, fmt.Errorf("колонка %q указана в сортировке несколько раз", field.Column)
+			}
+			seen[field.Column] = true
+
+			page.Sort = append(page.Sort, field)
+		}
+	}
+
+	return page, nil
+}
+
+// isPageError сообщает, что ошибка репозитория вызвана параметрами
+// пагинации клиента, а не сбоем БД.
+func isPageError(err error) bool {
+	return errors.Is(err, repository.ErrInvalidCursor) || errors.Is(err, repository.ErrCursorUnsupported)
+}
+
+// pageEnvelope собирает единый для всех списков ответ и выставляет заголовок
+// Link со ссылками на первую, предыдущую и следующую страницы.
+func pageEnvelope(c *gin.Context, items interface{}, page ds.PageRequest, info ds.PageInfo) gin.H {
+	var links []string
+
+	first := pageURL(c, func(query url.Values) {
+		query.Del("cursor")
+		query.Del("offset")
+	})
+	links = append(links, fmt.Sprintf(`<%s>; rel="first"`, first))
+
+	if page.Cursor == "" && page.Offset > 0 {
+		prev := pageURL(c, func(query url.Values) {
+			query.Set("offset", strconv.Itoa(max(page.Offset-page.Limit, 0)))
+		})
+		links = append(links, fmt.Sprintf(`<%s>; rel="prev"`, prev))
+	}
+
+	if info.HasMore {
+		next := pageURL(c, func(query url.Values) {
+			if info.NextCursor != "" {
+				query.Del("offset")
+				query.Set("cursor", info.NextCursor)
+			} else {
+				query.Set("offset", strconv.Itoa(page.Offset+page.Limit))
+			}
+		})
+		links = append(links, fmt.Sprintf(`<%s>; rel="next"`, next))
+	}
+
+	c.Header("Link", strings.Join(links, ", "))
+	c.Header("X-Total-Count", strconv.FormatInt(info.Total, 10))
+
+	return gin.H{
+		"items":       items,
+		"total":       info.Total,
+		"limit":       page.Limit,
+		"offset":      page.Offset,
+		"next_cursor": info.NextCursor,
+	}
+}
+
+func pageURL(c *gin.Context, modify func(query url.Values)) string {
+	u := *c.Request.URL
+	query := u.Query()
+	modify(query)
+	u.RawQuery = query.Encode()
+
+	return u.RequestURI()
+}
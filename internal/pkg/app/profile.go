@@ -0,0 +1,116 @@
+package app
+
+import (
+	"errors"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+
+	"sports_courses/internal/app/ds"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// учебная группа МГТУ: факультет с кафедрой, номер группы и необязательная буква, например ИУ5-31Б
+var studyGroupPattern = regexp.MustCompile(`^[А-ЯЁ]+\d*-\d+[А-ЯЁ]?$`)
+
+// @Summary      Мой профиль
+// @Description  Возвращает текущего пользователя вместе с профилем студента
+// @Tags         Профиль
+// @Produce      json
+// @Success      200  {object}  ds.Me
+// @Router       /me [get]
+func (a *Application) get_me(c *gin.Context) {
+	_userUUID, _ := c.Get("userUUID")
+	userUUID := _userUUID.(uuid.UUID)
+
+	user, err := a.repo.GetUserByID(userUUID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	profile, err := a.repo.GetStudentProfile(userUUID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, ds.Me{
+		UUID:    user.UUID,
+		Name:    user.Name,
+		Role:    user.Role,
+		Profile: profile,
+	})
+}
+
+// @Summary      Изменить мой профиль
+// @Description  Студент заполняет ФИО, учебную группу, факультет, курс, контакты и медицинскую группу
+// @Tags         Профиль
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  ds.StudentProfile
+// @Param request_body body ds.EditProfileRequestBody true "Профиль"
+// @Router       /me [put]
+func (a *Application) edit_me(c *gin.Context) {
+	var requestBody ds.EditProfileRequestBody
+
+	if err := c.BindJSON(&requestBody); err != nil {
+		c.String(http.StatusBadRequest, "Передан плохой json")
+		return
+	}
+
+	_userUUID, _ := c.Get("userUUID")
+	userUUID := _userUUID.(uuid.UUID)
+
+	profile := &ds.StudentProfile{
+		UserRefer:    userUUID,
+		FullName:     strings.TrimSpace(requestBody.FullName),
+		StudyGroup:   strings.ToUpper(strings.TrimSpace(requestBody.StudyGroup)),
+		Faculty:      strings.TrimSpace(requestBody.Faculty),
+		Year:         requestBody.Year,
+		Gender:       requestBody.Gender,
+		Email:        strings.TrimSpace(requestBody.Email),
+		Phone:        strings.TrimSpace(requestBody.Phone),
+		MedicalGroup: requestBody.MedicalGroup,
+	}
+
+	if err := validateProfile(profile); err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err := a.repo.SaveStudentProfile(profile)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, profile)
+}
+
+func validateProfile(profile *ds.StudentProfile) error {
+	if profile.StudyGroup != "" && !studyGroupPattern.MatchString(profile.StudyGroup) {
+		return errors.New("учебная группа должна быть в формате ИУ5-31Б")
+	}
+
+	if profile.Year < 0 || profile.Year > 6 {
+		return errors.New("курс обучения должен быть от 1 до 6")
+	}
+
+	if profile.Gender != "" && profile.Gender != ds.GenderMale && profile.Gender != ds.GenderFemale {
+		return errors.New("пол должен быть \"" + ds.GenderMale + "\" или \"" + ds.GenderFemale + "\"")
+	}
+
+	if profile.MedicalGroup != "" && !slices.Contains(ds.MedicalGroups, profile.MedicalGroup) {
+		return errors.New("медицинская группа должна быть одной из: " + strings.Join(ds.MedicalGroups, ", "))
+	}
+
+	if profile.Email != "" && !strings.Contains(profile.Email, "@") {
+		return errors.New("передан некорректный e-mail")
+	}
+
+	return nil
+}
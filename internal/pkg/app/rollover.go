@@ -0,0 +1,64 @@
+package app
+
+import (
+	"errors"
+	"net/http"
+
+	"sports_courses/internal/app/ds"
+	"sports_courses/internal/app/repository"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// @Summary      Перенести группы в новый семестр
+// @Description  Копирует выбранные (или все действующие) группы семестра в другой семестр вместе с расписанием, тренером, местом, вместимостью и картинками. С DryRun только показывает, что будет создано
+// @Tags         Семестры
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  ds.RolloverReport
+// @Failure      409  {object}  ds.RolloverReport
+// @Param request_body body ds.RolloverRequestBody true "Параметры переноса"
+// @Router       /term/rollover [post]
+func (a *Application) rollover_groups(c *gin.Context) {
+	var requestBody ds.RolloverRequestBody
+
+	if err := c.BindJSON(&requestBody); err != nil || requestBody.FromTermID == 0 || requestBody.ToTermID == 0 {
+		c.String(http.StatusBadRequest, "Нужно передать FromTermID и ToTermID")
+		return
+	}
+
+	for _, override := range requestBody.Overrides {
+		if err := validateGroupSessions(override.Sessions); err != nil {
+			c.String(http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	report, err := a.repo.RolloverGroups(requestBody)
+	if errors.Is(err, repository.ErrRolloverFailed) {
+		c.JSON(http.StatusConflict, report)
+		return
+	}
+
+	if errors.Is(err, repository.ErrRolloverSameTerm) || errors.Is(err, repository.ErrRolloverGroupMissing) {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.String(http.StatusNotFound, "Семестр не найден")
+		return
+	}
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	if !report.DryRun && report.Created > 0 {
+		a.invalidateGroupsCache(c.Request.Context())
+	}
+
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,182 @@
+package app
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"sports_courses/internal/app/ds"
+	"sports_courses/internal/app/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// @Summary      Получить правила записи
+// @Description  Возвращает правила, которые проверяются при записи в группы
+// @Tags         Правила записи
+// @Produce      json
+// @Success      200  {object}  string
+// @Param limit query int false "Размер страницы"
+// @Param offset query int false "Смещение от начала списка"
+// @Param cursor query string false "Курсор следующей страницы из next_cursor"
+// @Param sort query string false "Сортировка, например kind,id"
+// @Router       /rules [get]
+func (a *Application) get_rules(c *gin.Context) {
+	page, err := parsePageRequest(c, repository.RuleSortColumns)
+	if err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	rules, info, err := a.repo.GetRules(page)
+	if isPageError(err) {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, pageEnvelope(c, rules, page, info))
+}
+
+// @Summary      Добавить правило записи
+// @Description  Создаёт правило: max_groups_per_term, max_groups_per_course, medical_group, year_of_study или gender. Без Enabled правило включено
+// @Tags         Правила записи
+// @Accept       json
+// @Produce      json
+// @Success      201  {object}  ds.EnrollmentRule
+// @Param rule body ds.EnrollmentRule true "Правило"
+// @Router       /rule/add [post]
+func (a *Application) add_rule(c *gin.Context) {
+	// без Enabled в запросе правило создаётся включённым
+	rule := ds.EnrollmentRule{Enabled: true}
+
+	if err := c.BindJSON(&rule); err != nil {
+		c.String(http.StatusBadRequest, "Не получается распознать правило")
+		return
+	}
+
+	rule.ID = 0
+
+	err := a.repo.CreateRule(&rule)
+	if errors.Is(err, repository.ErrInvalidRule) {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, rule)
+}
+
+// @Summary      Редактировать правило записи
+// @Description  Заменяет правило целиком
+// @Tags         Правила записи
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  string
+// @Param rule body ds.EnrollmentRule true "Правило (должно содержать id)"
+// @Router       /rule/edit [put]
+func (a *Application) edit_rule(c *gin.Context) {
+	var rule ds.EnrollmentRule
+
+	if err := c.BindJSON(&rule); err != nil || rule.ID == 0 {
+		c.String(http.StatusBadRequest, "Не получается распознать правило")
+		return
+	}
+
+	err := a.repo.EditRule(&rule)
+	if errors.Is(err, repository.ErrInvalidRule) {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.String(http.StatusNotFound, "Правило не найдено")
+		return
+	}
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.String(http.StatusOK, "Правило было успешно изменено")
+}
+
+// @Summary      Удалить правило записи
+// @Description  Удаляет правило по id
+// @Tags         Правила записи
+// @Produce      json
+// @Success      200  {object}  string
+// @Param rule_id path int true "id правила"
+// @Router       /rule/delete/{rule_id} [delete]
+func (a *Application) delete_rule(c *gin.Context) {
+	rule_id, err := strconv.Atoi(c.Param("rule_id"))
+	if err != nil {
+		c.String(http.StatusBadRequest, "Не получается прочитать ID правила")
+		return
+	}
+
+	err = a.repo.DeleteRule(rule_id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.String(http.StatusOK, "Правило было успешно удалено")
+}
+
+// checkEnrollmentRules проверяет правила записи для enrollment_id с группами
+// group_ids. При нарушениях отвечает 422 со списком всех нарушенных правил.
+func (a *Application) checkEnrollmentRules(c *gin.Context, enrollment_id int, group_ids []int) bool {
+	violations, err := a.repo.CheckEnrollmentRules(enrollment_id, group_ids)
+	return respondRuleViolations(c, violations, err)
+}
+
+// checkNewEnrollmentRules - то же для записи, которая ещё не создана.
+func (a *Application) checkNewEnrollmentRules(c *gin.Context, userUUID uuid.UUID, group_ids []int) bool {
+	violations, err := a.repo.CheckNewEnrollmentRules(userUUID, group_ids)
+	return respondRuleViolations(c, violations, err)
+}
+
+func respondRuleViolations(c *gin.Context, violations []ds.RuleViolation, err error) bool {
+	if err != nil {
+		c.Error(err)
+		c.String(http.StatusInternalServerError, "Не получается проверить правила записи")
+		return false
+	}
+
+	if len(violations) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":      "Запись нарушает правила",
+			"violations": violations,
+		})
+		return false
+	}
+
+	return true
+}
+
+// enrollmentGroupIDs возвращает id групп, которые уже есть в записи.
+func (a *Application) enrollmentGroupIDs(enrollment_id int) ([]int, error) {
+	groups, err := a.repo.GetEnrollmentGroups(enrollment_id)
+	if err != nil {
+		return nil, err
+	}
+
+	group_ids := make([]int, 0, len(groups)+1)
+	for _, group := range groups {
+		group_ids = append(group_ids, int(group.ID))
+	}
+
+	return group_ids, nil
+}
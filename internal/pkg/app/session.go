@@ -0,0 +1,226 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"sports_courses/internal/app/ds"
+	"sports_courses/internal/app/repository"
+	"sports_courses/internal/app/role"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// defaultSchedulePeriod - на сколько дней вперёд показывается расписание без параметра to.
+const defaultSchedulePeriod = 30
+
+// @Summary      Отменить занятия
+// @Description  Отменяет одно занятие или все занятия группы за период и уведомляет зачисленных студентов. Без группы модератор отменяет занятия всех групп за период
+// @Tags         Посещаемость
+// @Accept       json
+// @Produce      json
+// @Success      200  {array}  ds.SessionInstance
+// @Param request_body body ds.CancelSessionsRequestBody true "Занятия и причина"
+// @Router       /session/cancel [put]
+func (a *Application) cancel_sessions(c *gin.Context) {
+	var requestBody ds.CancelSessionsRequestBody
+
+	if err := c.BindJSON(&requestBody); err != nil {
+		c.String(http.StatusBadRequest, "Не получается распознать json запрос")
+		return
+	}
+
+	requestBody.Reason = strings.TrimSpace(requestBody.Reason)
+	if requestBody.Reason == "" {
+		c.String(http.StatusBadRequest, "Нужно указать причину отмены")
+		return
+	}
+
+	if requestBody.SessionID != 0 {
+		if _, ok := a.attendanceSession(c, int(requestBody.SessionID)); !ok {
+			return
+		}
+	} else {
+		if requestBody.From.IsZero() || requestBody.To.IsZero() || requestBody.To.Before(requestBody.From) {
+			c.String(http.StatusBadRequest, "Нужно передать SessionID или период From - To")
+			return
+		}
+
+		if requestBody.GroupID == 0 {
+			_roleNumber, _ := c.Get("role")
+			if roleNumber, _ := _roleNumber.(role.Role); roleNumber == role.Coach {
+				c.String(http.StatusForbidden, "Тренер может отменять занятия только своих групп")
+				return
+			}
+		} else if !a.attendanceGroup(c, requestBody.GroupID) {
+			return
+		}
+	}
+
+	instances, err := a.repo.CancelSessions(requestBody)
+	if errors.Is(err, repository.ErrSessionCancelled) || errors.Is(err, repository.ErrSessionPast) {
+		c.String(http.StatusConflict, err.Error())
+		return
+	}
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, instances)
+}
+
+// @Summary      Перенести занятие
+// @Description  Переносит занятие на другой день, время или место и уведомляет зачисленных студентов
+// @Tags         Посещаемость
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  ds.SessionInstance
+// @Param request_body body ds.MoveSessionRequestBody true "Новое время и причина"
+// @Router       /session/move [put]
+func (a *Application) move_session(c *gin.Context) {
+	var requestBody ds.MoveSessionRequestBody
+
+	if err := c.BindJSON(&requestBody); err != nil || requestBody.SessionID == 0 || requestBody.Date.IsZero() {
+		c.String(http.StatusBadRequest, "Нужно передать SessionID и новую дату")
+		return
+	}
+
+	requestBody.Reason = strings.TrimSpace(requestBody.Reason)
+	if requestBody.Reason == "" {
+		c.String(http.StatusBadRequest, "Нужно указать причину переноса")
+		return
+	}
+
+	weekday := int(requestBody.Date.Weekday())
+	if weekday == 0 {
+		weekday = 7
+	}
+
+	err := validateGroupSessions([]ds.GroupSession{{Weekday: weekday, StartTime: requestBody.StartTime, EndTime: requestBody.EndTime}})
+	if err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if requestBody.LocationID != nil {
+		if _, err := a.repo.GetLocationByID(int(*requestBody.LocationID)); err != nil {
+			c.String(http.StatusNotFound, "Место не найдено")
+			return
+		}
+	}
+
+	if _, ok := a.attendanceSession(c, int(requestBody.SessionID)); !ok {
+		return
+	}
+
+	instance, err := a.repo.MoveSession(requestBody)
+	if errors.Is(err, repository.ErrSessionCancelled) || errors.Is(err, repository.ErrSessionPast) || errors.Is(err, repository.ErrSessionSlotTaken) {
+		c.String(http.StatusConflict, err.Error())
+		return
+	}
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.String(http.StatusNotFound, "Занятие не найдено")
+		return
+	}
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, instance)
+}
+
+// @Summary      Моё расписание
+// @Description  Возвращает занятия групп студента за период, включая отменённые и перенесённые. С format=ics - календарь в формате iCalendar
+// @Tags         Посещаемость
+// @Produce      json
+// @Produce      text/calendar
+// @Success      200  {array}  ds.ScheduleEntry
+// @Param from query string false "Начало периода ГГГГ-ММ-ДД (по умолчанию сегодня)"
+// @Param to query string false "Конец периода ГГГГ-ММ-ДД (по умолчанию через 30 дней)"
+// @Param format query string false "ics, чтобы получить календарь"
+// @Router       /me/schedule [get]
+func (a *Application) get_my_schedule(c *gin.Context) {
+	from, to, err := parsePeriod(c)
+	if err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if from.IsZero() {
+		from = time.Now()
+	}
+
+	if to.IsZero() {
+		to = from.AddDate(0, 0, defaultSchedulePeriod)
+	}
+
+	_userUUID, _ := c.Get("userUUID")
+	userUUID := _userUUID.(uuid.UUID)
+
+	entries, err := a.repo.GetUserSchedule(userUUID, from, to)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	if c.Query("format") == "ics" {
+		c.Header("Content-Disposition", "attachment; filename=schedule.ics")
+		c.Data(http.StatusOK, "text/calendar; charset=utf-8", []byte(scheduleCalendar(entries)))
+		return
+	}
+
+	c.JSON(http.StatusOK, entries)
+}
+
+// scheduleCalendar собирает расписание в формате iCalendar. Отменённые
+// занятия остаются в календаре со статусом CANCELLED, чтобы календарь
+// студента убрал их у себя.
+func scheduleCalendar(entries []ds.ScheduleEntry) string {
+	var b strings.Builder
+
+	b.WriteString("BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:-//sports_courses//schedule//RU\r\nCALSCALE:GREGORIAN\r\n")
+
+	stamp := time.Now().UTC().Format("20060102T150405Z")
+	for _, entry := range entries {
+		date := entry.Date.Format("20060102")
+		location := strings.TrimSpace(entry.Building + " " + entry.Room)
+
+		b.WriteString("BEGIN:VEVENT\r\n")
+		fmt.Fprintf(&b, "UID:session-%d@sports_courses\r\n", entry.SessionID)
+		fmt.Fprintf(&b, "DTSTAMP:%s\r\n", stamp)
+		fmt.Fprintf(&b, "DTSTART:%sT%s00\r\n", date, strings.ReplaceAll(entry.StartTime, ":", ""))
+		fmt.Fprintf(&b, "DTEND:%sT%s00\r\n", date, strings.ReplaceAll(entry.EndTime, ":", ""))
+		fmt.Fprintf(&b, "SUMMARY:%s\r\n", escapeCalendarText(entry.GroupTitle))
+		if location != "" {
+			fmt.Fprintf(&b, "LOCATION:%s\r\n", escapeCalendarText(location))
+		}
+		if entry.Reason != "" {
+			fmt.Fprintf(&b, "DESCRIPTION:%s\r\n", escapeCalendarText(entry.Reason))
+		}
+		if entry.Cancelled {
+			b.WriteString("STATUS:CANCELLED\r\n")
+		} else {
+			b.WriteString("STATUS:CONFIRMED\r\n")
+		}
+		b.WriteString("END:VEVENT\r\n")
+	}
+
+	b.WriteString("END:VCALENDAR\r\n")
+
+	return b.String()
+}
+
+func escapeCalendarText(value string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+	return replacer.Replace(value)
+}
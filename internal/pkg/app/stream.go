@@ -0,0 +1,230 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"sports_courses/internal/app/ds"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	eventRelayInterval  = time.Second
+	streamRetryInterval = 5 * time.Second
+	streamHeartbeat     = 15 * time.Second
+	// streamClientBuffer - сколько событий может ждать медленный клиент, прежде
+	// чем его отключат. Переподключившись с Last-Event-ID, он получит пропущенное
+	streamClientBuffer = 64
+)
+
+type streamClient struct {
+	userUUID uuid.UUID
+	events   chan ds.StreamEvent
+}
+
+// streamHub раздаёт события из redis клиентам, подключённым к этому
+// экземпляру сервиса. На весь экземпляр открыта одна подписка в redis.
+type streamHub struct {
+	mu      sync.Mutex
+	clients map[*streamClient]struct{}
+}
+
+func newStreamHub() *streamHub {
+	return &streamHub{clients: map[*streamClient]struct{}{}}
+}
+
+func (h *streamHub) add(userUUID uuid.UUID) *streamClient {
+	client := &streamClient{userUUID: userUUID, events: make(chan ds.StreamEvent, streamClientBuffer)}
+
+	h.mu.Lock()
+	h.clients[client] = struct{}{}
+	h.mu.Unlock()
+
+	return client
+}
+
+func (h *streamHub) remove(client *streamClient) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.clients[client]; ok {
+		delete(h.clients, client)
+		close(client.events)
+	}
+}
+
+func (h *streamHub) broadcast(event ds.StreamEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for client := range h.clients {
+		if event.UserRefer != nil && *event.UserRefer != client.userUUID {
+			continue
+		}
+
+		select {
+		case client.events <- event:
+		default:
+			delete(h.clients, client)
+			close(client.events)
+		}
+	}
+}
+
+// startEventStream подписывает хаб на события из redis и запускает отправку
+// сохранённых в базе событий в поток.
+func (a *Application) startEventStream(ctx context.Context) {
+	go func() {
+		for ctx.Err() == nil {
+			err := a.redis.SubscribeStreamEvents(ctx, a.stream.broadcast)
+			if err != nil && ctx.Err() == nil {
+				log.Println("Подписка на поток событий прервалась:", err)
+			}
+			time.Sleep(streamRetryInterval)
+		}
+	}()
+
+	go func() {
+		ticker := time.NewTicker(eventRelayInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if !a.redis.Available() {
+					continue
+				}
+
+				if _, err := a.repo.RelayEvents(a.publishDomainEvent(ctx)); err != nil {
+					log.Println("Не получается отправить события в поток:", err)
+				}
+			}
+		}
+	}()
+}
+
+// publishDomainEvent отправляет в поток событие, а если по нему создано
+// уведомление - ещё и событие notification.
+func (a *Application) publishDomainEvent(ctx context.Context) func(ds.DomainEvent, *ds.Notification) error {
+	return func(event ds.DomainEvent, notification *ds.Notification) error {
+		userUUID := event.UserRefer
+
+		err := a.redis.PublishStreamEvent(ctx, &ds.StreamEvent{Type: event.Type, UserRefer: &userUUID, Data: event.Payload})
+		if err != nil || notification == nil {
+			return err
+		}
+
+		return a.redis.PublishStreamEvent(ctx, &ds.StreamEvent{Type: ds.EventNotification, UserRefer: &userUUID, Data: notification})
+	}
+}
+
+// publishGroupSeats сообщает всем клиентам, что в группах изменилось число мест.
+func (a *Application) publishGroupSeats(ctx context.Context, seats ...ds.GroupSeats) {
+	for _, group := range seats {
+		err := a.redis.PublishStreamEvent(ctx, &ds.StreamEvent{Type: ds.EventGroupSeatsChanged, Data: group})
+		if err != nil {
+			log.Println("Не получается отправить в поток изменение мест группы:", err)
+			return
+		}
+	}
+}
+
+// @Summary      Поток событий
+// @Description  Server-Sent Events: изменения статуса своих записей, новые уведомления, отмены и переносы занятий и изменения числа мест в группах. Переподключение с Last-Event-ID досылает пропущенные события
+// @Tags         События
+// @Produce      text/event-stream
+// @Success      200  {object}  ds.StreamEvent
+// @Param Last-Event-ID header int false "id последнего полученного события"
+// @Router       /events [get]
+func (a *Application) get_events(c *gin.Context) {
+	if !a.redis.Available() {
+		c.String(http.StatusServiceUnavailable, "Поток событий временно недоступен")
+		return
+	}
+
+	lastID := int64(0)
+	if param := c.GetHeader("Last-Event-ID"); param != "" {
+		value, err := strconv.ParseInt(param, 10, 64)
+		if err != nil {
+			c.String(http.StatusBadRequest, "Передан некорректный Last-Event-ID")
+			return
+		}
+		lastID = value
+	}
+
+	_userUUID, _ := c.Get("userUUID")
+	userUUID := _userUUID.(uuid.UUID)
+
+	// клиент подписывается до чтения журнала, чтобы не потерять события между ними
+	client := a.stream.add(userUUID)
+	defer a.stream.remove(client)
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	fmt.Fprintf(c.Writer, "retry: %d\n\n", streamRetryInterval.Milliseconds())
+
+	// досланные из журнала события могут прийти ещё раз через подписку
+	replayed := map[int64]bool{}
+	if lastID > 0 {
+		missed, err := a.redis.StreamEventsSince(c.Request.Context(), lastID)
+		if err != nil {
+			log.Println("Не получается прочитать пропущенные события:", err)
+		}
+
+		for _, event := range missed {
+			if event.UserRefer == nil || *event.UserRefer == userUUID {
+				writeStreamEvent(c, event)
+				replayed[event.ID] = true
+			}
+		}
+	}
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(c.Writer, ": ping\n\n")
+			c.Writer.Flush()
+		case event, ok := <-client.events:
+			if !ok {
+				return
+			}
+
+			if replayed[event.ID] {
+				continue
+			}
+
+			writeStreamEvent(c, event)
+			c.Writer.Flush()
+		}
+	}
+}
+
+func writeStreamEvent(c *gin.Context, event ds.StreamEvent) {
+	data, err := json.Marshal(event.Data)
+	if err != nil {
+		log.Println("Не получается закодировать событие:", err)
+		return
+	}
+
+	fmt.Fprintf(c.Writer, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+}
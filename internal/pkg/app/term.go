@@ -0,0 +1,201 @@
+package app
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"sports_courses/internal/app/ds"
+	"sports_courses/internal/app/repository"
+	"sports_courses/internal/app/role"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// allTermsParam - значение параметра term, отключающее фильтр по семестру.
+const allTermsParam = "all"
+
+// @Summary      Получить семестры
+// @Description  Возвращает список семестров, по умолчанию начиная с последнего
+// @Tags         Семестры
+// @Produce      json
+// @Success      200  {object}  string
+// @Param limit query int false "Размер страницы"
+// @Param offset query int false "Смещение от начала списка"
+// @Param cursor query string false "Курсор следующей страницы из next_cursor"
+// @Param sort query string false "Сортировка, например -start_date"
+// @Router       /terms [get]
+func (a *Application) get_terms(c *gin.Context) {
+	page, err := parsePageRequest(c, repository.TermSortColumns)
+	if err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	terms, info, err := a.repo.GetTerms(page)
+	if isPageError(err) {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, pageEnvelope(c, terms, page, info))
+}
+
+// @Summary      Текущий семестр
+// @Description  Возвращает семестр, который идёт сейчас
+// @Tags         Семестры
+// @Produce      json
+// @Success      200  {object}  ds.Term
+// @Router       /term/current [get]
+func (a *Application) get_current_term(c *gin.Context) {
+	term, err := a.repo.GetCurrentTerm()
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	if term == nil {
+		c.String(http.StatusNotFound, "Сейчас не идёт ни один семестр")
+		return
+	}
+
+	c.JSON(http.StatusOK, term)
+}
+
+// @Summary      Добавить семестр
+// @Description  Создаёт семестр с датами проведения и окном записи
+// @Tags         Семестры
+// @Accept       json
+// @Produce      json
+// @Success      201  {object}  ds.Term
+// @Param term body ds.Term true "Семестр"
+// @Router       /term/add [post]
+func (a *Application) add_term(c *gin.Context) {
+	var term ds.Term
+
+	if err := c.BindJSON(&term); err != nil {
+		c.String(http.StatusBadRequest, "Не получается распознать семестр")
+		return
+	}
+
+	term.ID = 0
+	term.Name = strings.TrimSpace(term.Name)
+	if term.Name == "" {
+		c.String(http.StatusBadRequest, "Название семестра не может быть пустым")
+		return
+	}
+
+	err := a.repo.CreateTerm(&term)
+	if errors.Is(err, repository.ErrInvalidTermDates) {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err != nil {
+		c.String(http.StatusConflict, "Не получается создать семестр\n"+err.Error())
+		return
+	}
+
+	c.JSON(http.StatusCreated, term)
+}
+
+// @Summary      Редактировать семестр
+// @Description  Находит семестр по id и обновляет переданные поля
+// @Tags         Семестры
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  string
+// @Param term body ds.Term true "Семестр (должен содержать id)"
+// @Router       /term/edit [put]
+func (a *Application) edit_term(c *gin.Context) {
+	var term ds.Term
+
+	if err := c.BindJSON(&term); err != nil || term.ID == 0 {
+		c.String(http.StatusBadRequest, "Не получается распознать семестр")
+		return
+	}
+
+	term.Name = strings.TrimSpace(term.Name)
+
+	err := a.repo.EditTerm(&term)
+	if errors.Is(err, repository.ErrInvalidTermDates) {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.String(http.StatusNotFound, "Семестр не найден")
+		return
+	}
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	a.invalidateGroupsCache(c.Request.Context())
+
+	c.String(http.StatusOK, "Семестр был успешно изменён")
+}
+
+// parseTermFilter читает параметр term: id семестра или "all". Без параметра
+// используется семестр, который возвращает defaultTerm, а если его нет -
+// фильтр не накладывается. Если ответить не удалось, ответ уже отправлен.
+func (a *Application) parseTermFilter(c *gin.Context, defaultTerm func() (*ds.Term, error)) (uint, bool) {
+	param := c.Query("term")
+
+	if param == allTermsParam {
+		return 0, true
+	}
+
+	if param != "" {
+		value, err := strconv.ParseUint(param, 10, 64)
+		if err != nil {
+			c.String(http.StatusBadRequest, "Передан некорректный id семестра")
+			return 0, false
+		}
+		return uint(value), true
+	}
+
+	term, err := defaultTerm()
+	if err != nil {
+		c.Error(err)
+		return 0, false
+	}
+
+	if term == nil {
+		return 0, true
+	}
+
+	return term.ID, true
+}
+
+// enrollmentWindowOpen проверяет, что запись в семестр term сейчас открыта.
+// Модераторы и администраторы работают с записями вне окна записи, группы
+// без семестра окном не ограничены.
+func enrollmentWindowOpen(c *gin.Context, term *ds.Term) bool {
+	if term == nil {
+		return true
+	}
+
+	_roleNumber, _ := c.Get("role")
+	if roleNumber, ok := _roleNumber.(role.Role); ok && (roleNumber == role.Moderator || roleNumber == role.Admin) {
+		return true
+	}
+
+	if term.EnrollmentOpen(time.Now()) {
+		return true
+	}
+
+	c.String(http.StatusForbidden, "Запись на семестр \""+term.Name+"\" открыта с "+
+		term.EnrollmentOpensAt.Format("02.01.2006 15:04")+" до "+term.EnrollmentClosesAt.Format("02.01.2006 15:04"))
+	return false
+}
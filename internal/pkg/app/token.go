@@ -0,0 +1,86 @@
+package app
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+const (
+	// revokedTokenTTL - сколько хранится отзыв токена, столько же живёт сам токен
+	revokedTokenTTL = time.Hour
+	// revocationSyncInterval - как часто отзывы из базы переносятся в redis
+	revocationSyncInterval = 10 * time.Second
+	revocationSyncBatch    = 500
+)
+
+// revokeToken сохраняет отзыв токена в базе и в redis. Отзыв считается
+// выполненным, если он попал в базу: если redis недоступен, токен попадёт
+// в его блэклист при следующей синхронизации, а до тех пор проверяется по базе.
+func (a *Application) revokeToken(ctx context.Context, token string) error {
+	if err := a.repo.RevokeToken(token, time.Now().Add(revokedTokenTTL)); err != nil {
+		return err
+	}
+
+	if err := a.redis.WriteJWTToBlackList(ctx, token, revokedTokenTTL); err != nil {
+		log.Println("Отзыв токена сохранён в базе, в redis он попадёт позже:", err)
+		return nil
+	}
+
+	if err := a.repo.MarkRevocationSynced(token); err != nil {
+		log.Println("Не получается отметить отзыв токена записанным в redis:", err)
+	}
+
+	return nil
+}
+
+// startRevocationSync переносит в redis отзывы токенов, сохранённые, пока
+// redis был недоступен, и удаляет из базы истёкшие отзывы.
+func (a *Application) startRevocationSync(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(revocationSyncInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				a.syncRevocations(ctx)
+			}
+		}
+	}()
+}
+
+func (a *Application) syncRevocations(ctx context.Context) {
+	if err := a.repo.DeleteExpiredRevocations(); err != nil {
+		log.Println("Не получается удалить истёкшие отзывы токенов:", err)
+	}
+
+	if !a.redis.Available() {
+		return
+	}
+
+	tokens, err := a.repo.GetUnsyncedRevocations(revocationSyncBatch)
+	if err != nil {
+		log.Println("Не получается получить отзывы токенов:", err)
+		return
+	}
+
+	for _, token := range tokens {
+		ttl := time.Until(token.ExpiresAt)
+		if ttl <= 0 {
+			continue
+		}
+
+		if err := a.redis.WriteJWTToBlackList(ctx, token.Token, ttl); err != nil {
+			log.Println("Не получается записать отзыв токена в redis:", err)
+			return
+		}
+
+		if err := a.repo.MarkRevocationSynced(token.Token); err != nil {
+			log.Println("Не получается отметить отзыв токена записанным в redis:", err)
+			return
+		}
+	}
+}